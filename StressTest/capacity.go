@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
+)
+
+const capacityUsage = "Usage: capacity --url=<url>|--targets=<file>|--scenario=<file>|--grpc=<host:port> --concurrency=<concurrency> --threshold=<slo> [--start-rate=<rps>] [--step-rate=<rps>] [--max-rate=<rps>] [--step-duration=<duration>] [--precision=<ratio>]"
+
+// runCapacity searches for the highest rate the target sustains within the
+// --threshold SLO and returns the process exit code.
+func runCapacity(args []string) int {
+	var opts Options
+	fs := newFlagSet("stress-test capacity", &opts)
+	search := loadtest.CapacitySearch{Precision: 0.05}
+	fs.Float64Var(&search.Start, "start-rate", 10, "First rate of the search, in requests per second")
+	fs.Float64Var(&search.Step, "step-rate", 0, "Rate increase between ramp steps (default the start rate)")
+	fs.Float64Var(&search.Max, "max-rate", 0, "Highest rate tried (0 means no limit)")
+	fs.DurationVar(&search.StepDuration, "step-duration", 30*time.Second, "How long each rate is held")
+	fs.Var((*ratioFlag)(&search.Precision), "precision", "Stop when the passing and failing rates are this close, e.g. 5%")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage(err)
+	}
+	if search.Step == 0 {
+		search.Step = search.Start
+	}
+	search.Concurrency = opts.Concurrency
+	search.SLO = opts.Thresholds
+
+	if err := opts.capacityConfig(); err != nil {
+		fmt.Println("All parameters are required and must be valid")
+		fmt.Println(capacityUsage)
+		fmt.Println(err)
+		return 1
+	}
+
+	target, description, err := opts.NewTarget(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer closeTarget(target)
+	if search.Client, err = opts.NewClient(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	search.Target = target
+
+	console := io.Writer(os.Stdout)
+	var out io.Writer
+	if opts.Output != "" {
+		out = os.Stdout
+		if opts.OutputFile != "" {
+			f, err := os.Create(opts.OutputFile)
+			if err != nil {
+				fmt.Printf("Error creating output file: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		} else {
+			console = os.Stderr
+		}
+	}
+
+	fmt.Fprintf(console, "Searching the capacity of %s\n", description)
+	fmt.Fprintf(console, "Concurrency: %d\n", search.Concurrency)
+	fmt.Fprintf(console, "Step duration: %v\n", search.StepDuration)
+	fmt.Fprintf(console, "SLO: %s\n", opts.Thresholds.String())
+	fmt.Fprintln(console, "--------------------------------------------------")
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var progress *loadtest.Progress
+	stopProgress := func() {}
+	search.OnStepStart = func(cfg loadtest.LoadConfig, start time.Time) {
+		progress = loadtest.NewProgress(cfg, start)
+		stopProgress = startProgress(progress.Line, opts.Progress)
+	}
+	search.OnResult = func(result loadtest.Result) {
+		progress.Add(result)
+	}
+	search.OnStep = func(step loadtest.CapacityStep) {
+		stopProgress()
+		stopProgress = func() {}
+		printCapacityStep(console, step)
+	}
+	result, err := search.Run(ctx)
+	stopProgress()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	if result.Interrupted {
+		fmt.Fprintln(console, "Interrupted: the unfinished step is not part of the result")
+	}
+	loadtest.PrintCapacity(console, result)
+	if out != nil {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result.Summary()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing json report: %v\n", err)
+			return 1
+		}
+	}
+	if result.Knee == 0 {
+		return exitThresholdsFailed
+	}
+	return 0
+}
+
+// capacityConfig checks the flags that do not apply to a capacity search,
+// which sets the rate and duration of every step itself.
+func (o *Options) capacityConfig() error {
+	switch {
+	case countSet(o.URL, o.Targets, o.Scenario, o.GRPCTarget) != 1:
+		return fmt.Errorf("exactly one of --url, --targets, --scenario or --grpc must be set")
+	case o.Pick != loadtest.PickRandom && o.Targets == "":
+		return fmt.Errorf("--pick needs --targets")
+	case o.Streaming():
+		return fmt.Errorf("--ws and --sse are not available in capacity mode")
+	case o.Replay != "":
+		return fmt.Errorf("--replay is not available in capacity mode")
+	case o.Requests != 0 || o.Duration != 0 || o.Rate != 0 || o.Stages != "":
+		return fmt.Errorf("--requests, --duration, --rate and --stages are set by the search; use --step-duration and the rate flags")
+	case o.Output != "" && o.Output != "json":
+		return fmt.Errorf("capacity mode only writes json output")
+	case o.HTML != "" || o.Pushgateway != "" || o.OTLP != "":
+		return fmt.Errorf("--html, --pushgateway and --otlp are not available in capacity mode")
+	case len(o.Thresholds) == 0:
+		return fmt.Errorf("at least one --threshold is required as the SLO")
+	}
+	return nil
+}
+
+func printCapacityStep(w io.Writer, step loadtest.CapacityStep) {
+	verdict := "PASS"
+	if !step.Passed {
+		var failed []string
+		for _, r := range step.Thresholds {
+			if !r.Passed {
+				failed = append(failed, fmt.Sprintf("%s (actual: %s)", r.Expression, r.Actual))
+			}
+		}
+		if achieved := step.Report.RequestsPerSecond(); achieved < loadtest.MinThroughput*step.Rate {
+			failed = append(failed, fmt.Sprintf("achieved %.2f rps", achieved))
+		}
+		verdict = "FAIL " + strings.Join(failed, ", ")
+	}
+	fmt.Fprintf(w, "[%s] %.2f rps: achieved %.2f rps, p95 %v, %s\n",
+		step.Phase, step.Rate, step.Report.RequestsPerSecond(),
+		step.Report.Stats.Latency.Percentile(95).Round(time.Microsecond), verdict)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
+)
+
+// exitRegressions is the exit code of a comparison that found regressions.
+const exitRegressions = 2
+
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("stress-test compare", flag.ContinueOnError)
+	tolerances := loadtest.CompareTolerances{Latency: 0.1, RPS: 0.1, ErrorRate: 0.01}
+	fs.Var((*ratioFlag)(&tolerances.Latency), "latency-tolerance", "Allowed latency increase, e.g. 10%")
+	fs.Var((*ratioFlag)(&tolerances.RPS), "rps-tolerance", "Allowed throughput decrease, e.g. 10%")
+	fs.Var((*ratioFlag)(&tolerances.ErrorRate), "error-rate-tolerance", "Allowed error rate increase in percentage points, e.g. 1%")
+	fs.Float64Var(&tolerances.Alpha, "alpha", 0.05, "Significance level of the latency distribution test")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage(err)
+	}
+	if fs.NArg() != 2 {
+		fmt.Println("Usage: compare [flags] <baseline.json> <candidate.json>")
+		return 1
+	}
+
+	baseline, err := loadSummary(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	candidate, err := loadSummary(fs.Arg(1))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	comparison := loadtest.CompareSummaries(baseline, candidate, tolerances)
+	fmt.Printf("Comparing %s (baseline) with %s (candidate)\n", fs.Arg(0), fs.Arg(1))
+	loadtest.PrintComparison(os.Stdout, comparison, tolerances)
+	if comparison.Regressions() > 0 {
+		return exitRegressions
+	}
+	return 0
+}
+
+func loadSummary(path string) (loadtest.Summary, error) {
+	var summary loadtest.Summary
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return summary, err
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return summary, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return summary, nil
+}
+
+// ratioFlag is a flag holding a percentage ("10%") or a fraction ("0.1").
+type ratioFlag float64
+
+func (r *ratioFlag) String() string {
+	return strconv.FormatFloat(float64(*r)*100, 'f', -1, 64) + "%"
+}
+
+func (r *ratioFlag) Set(value string) error {
+	v, err := loadtest.ParseRatio(value)
+	if err != nil {
+		return err
+	}
+	*r = ratioFlag(v)
+	return nil
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
+)
+
+// Distributed mode: a coordinator splits the plan across agents over plain
+// HTTP. POST /run carries the command line arguments of an agent's share and
+// answers with a stream of newline-delimited agentMessage values holding the
+// stats gathered since the previous message; POST /stop ends the run early.
+// Agents need the same files (scenarios, bodies, data, protos) as a local run.
+
+// agentFlushInterval is how often agents send partial stats.
+const agentFlushInterval = 500 * time.Millisecond
+
+type agentPlan struct {
+	Args []string `json:"args"`
+}
+
+type agentMessage struct {
+	Stats    *loadtest.Stats `json:"stats,omitempty"`
+	Done     bool            `json:"done,omitempty"`
+	Duration time.Duration   `json:"duration,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("stress-test agent", flag.ContinueOnError)
+	listen := fs.String("listen", ":7070", "Address the agent listens on")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage(err)
+	}
+
+	fmt.Printf("Agent listening on %s\n", *listen)
+	if err := http.ListenAndServe(*listen, NewAgent().Handler()); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// Agent runs one plan at a time on behalf of a coordinator.
+type Agent struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func NewAgent() *Agent {
+	return &Agent{}
+}
+
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /run", a.handleRun)
+	mux.HandleFunc("POST /stop", a.handleStop)
+	return mux
+}
+
+func (a *Agent) handleStop(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+	a.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Agent) handleRun(w http.ResponseWriter, r *http.Request) {
+	var plan agentPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var opts Options
+	fs := newFlagSet("agent", &opts)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(plan.Args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg, err := opts.LoadConfig()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.mu.Lock()
+	if a.cancel != nil {
+		a.mu.Unlock()
+		http.Error(w, "agent is already running a test", http.StatusConflict)
+		return
+	}
+	a.cancel = cancel
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.cancel = nil
+		a.mu.Unlock()
+	}()
+
+	// A coordinator that goes away stops the run as well
+	go func() {
+		select {
+		case <-r.Context().Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	target, _, err := opts.NewTarget(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer closeTarget(target)
+	client, err := opts.NewClient()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	send := func(msg agentMessage) {
+		encoder.Encode(msg)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var mu sync.Mutex
+	delta := loadtest.NewStats()
+	takeDelta := func() *loadtest.Stats {
+		mu.Lock()
+		defer mu.Unlock()
+		taken := delta
+		delta = loadtest.NewStats()
+		return taken
+	}
+
+	startTime := time.Now()
+	done := make(chan struct{})
+	runner := loadtest.Runner{
+		Target: target,
+		Config: cfg,
+		Client: client,
+		OnResult: func(result loadtest.Result) {
+			mu.Lock()
+			delta.Add(result)
+			mu.Unlock()
+		},
+	}
+	var runErr error
+	go func() {
+		defer close(done)
+		_, runErr = runner.Run(ctx)
+	}()
+
+	ticker := time.NewTicker(agentFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			send(agentMessage{Stats: takeDelta()})
+		case <-done:
+			msg := agentMessage{Stats: takeDelta(), Done: true, Duration: time.Since(startTime)}
+			if runErr != nil {
+				msg.Error = runErr.Error()
+			}
+			send(msg)
+			return
+		}
+	}
+}
+
+func runCoordinator(args []string) int {
+	var opts Options
+	fs := newFlagSet("stress-test coordinator", &opts)
+	agentList := fs.String("agents", "", "Comma separated host:port list of agents")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage(err)
+	}
+
+	cfg, err := opts.LoadConfig()
+	if err == nil && *agentList == "" {
+		err = fmt.Errorf("--agents is required")
+	}
+	agents := strings.Split(*agentList, ",")
+	if err == nil && cfg.Requests > 0 && cfg.Requests < len(agents) {
+		err = fmt.Errorf("--requests must be at least the number of agents")
+	}
+	if err == nil && opts.Replay != "" {
+		err = fmt.Errorf("--replay is not available in distributed mode")
+	}
+	if err == nil && opts.Streaming() {
+		err = fmt.Errorf("--ws and --sse are not available in distributed mode")
+	}
+	if err == nil && opts.Output == "csv" {
+		err = fmt.Errorf("per-request csv output is not available in distributed mode")
+	}
+	if err != nil {
+		fmt.Println("All parameters are required and must be valid")
+		fmt.Println("Usage: coordinator --agents=<host:port,...> " + strings.TrimPrefix(usage, "Usage: "))
+		fmt.Println(err)
+		return 1
+	}
+
+	sink, err := newReportSink(&opts)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer sink.Close()
+
+	fmt.Fprintf(sink.console, "Agents: %s\n", strings.Join(agents, ", "))
+	opts.printHeader(sink.console, opts.description(), cfg)
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// Thresholds, reports and exporters only run on the coordinator
+	base := withoutFlags(fs, args, "agents", "threshold", "output", "output-file",
+		"html", "pushgateway", "otlp", "export-interval")
+	plans := make([][]string, len(agents))
+	for i := range agents {
+		plans[i] = agentArgs(base, cfg, len(agents), i)
+	}
+
+	startTime := time.Now()
+	sink.Start(startTime)
+	progress := loadtest.NewProgress(cfg, startTime)
+	stopProgress := startProgress(progress.Line, opts.Progress)
+	stats, err := runDistributed(ctx, agents, plans, func(delta *loadtest.Stats) {
+		progress.AddStats(delta)
+		sink.AddStats(time.Now(), delta)
+	})
+	totalDuration := time.Since(startTime)
+	stopProgress()
+	if err != nil {
+		fmt.Fprintln(sink.console, err)
+		return 1
+	}
+
+	return sink.Finish(&loadtest.Report{
+		Config:      cfg,
+		StartedAt:   startTime,
+		Duration:    totalDuration,
+		Interrupted: ctx.Err() != nil,
+		Stats:       stats,
+	})
+}
+
+// runDistributed sends each agent its plan and merges the streamed stats.
+// onStats, when not nil, receives every partial delta. Canceling ctx asks the
+// agents to stop; their final stats are still collected.
+func runDistributed(ctx context.Context, agents []string, plans [][]string, onStats func(*loadtest.Stats)) (*loadtest.Stats, error) {
+	// One failing agent aborts the others: closing their streams stops them
+	abortCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+
+	total := loadtest.NewStats()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+
+	for i, agent := range agents {
+		wg.Add(1)
+		go func(i int, agent string) {
+			defer wg.Done()
+			err := runOnAgent(abortCtx, agent, plans[i], func(delta *loadtest.Stats) {
+				mu.Lock()
+				total.Merge(delta)
+				mu.Unlock()
+				if onStats != nil {
+					onStats(delta)
+				}
+			})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("agent %s: %w", agent, err)
+				}
+				mu.Unlock()
+				abort()
+			}
+		}(i, agent)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		for _, agent := range agents {
+			if resp, err := http.Post(agentURL(agent, "/stop"), "application/json", nil); err == nil {
+				resp.Body.Close()
+			}
+		}
+		<-finished
+	}
+
+	return total, firstErr
+}
+
+func runOnAgent(ctx context.Context, agent string, args []string, onStats func(*loadtest.Stats)) error {
+	body, err := json.Marshal(agentPlan{Args: args})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agentURL(agent, "/run"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		msg := agentMessage{Stats: loadtest.NewStats()}
+		if err := decoder.Decode(&msg); err != nil {
+			return fmt.Errorf("reading results: %w", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("%s", msg.Error)
+		}
+		onStats(msg.Stats)
+		if msg.Done {
+			return nil
+		}
+	}
+}
+
+func agentURL(agent, path string) string {
+	if strings.HasPrefix(agent, "http://") || strings.HasPrefix(agent, "https://") {
+		return strings.TrimSuffix(agent, "/") + path
+	}
+	return "http://" + agent + path
+}
+
+// agentArgs returns the arguments of agent i out of n: request count,
+// concurrency and rates are split, the rest is passed through.
+func agentArgs(base []string, cfg loadtest.LoadConfig, n, i int) []string {
+	args := append([]string{}, base...)
+	if cfg.Requests > 0 {
+		args = append(args, fmt.Sprintf("--requests=%d", share(cfg.Requests, n, i)))
+	}
+	concurrency := share(cfg.Concurrency, n, i)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	args = append(args, fmt.Sprintf("--concurrency=%d", concurrency))
+	if cfg.Rate > 0 {
+		args = append(args, fmt.Sprintf("--rate=%g", cfg.Rate/float64(n)))
+	}
+	if len(cfg.Stages) > 0 {
+		stages := make([]string, len(cfg.Stages))
+		for j, s := range cfg.Stages {
+			stages[j] = fmt.Sprintf("%v:%g", s.Duration, s.Target/float64(n))
+		}
+		args = append(args, "--stages="+strings.Join(stages, ","))
+	}
+	return append(args, "--progress=0")
+}
+
+// share splits total into n parts that differ by at most one.
+func share(total, n, i int) int {
+	part := total / n
+	if i < total%n {
+		part++
+	}
+	return part
+}
+
+// withoutFlags removes the named flags, with their values, from args as
+// parsed by fs. Like fs, it reads a flag's value from the next argument
+// unless the flag is boolean or written as -name=value, so a value that
+// looks like a flag name is never taken for one.
+func withoutFlags(fs *flag.FlagSet, args []string, names ...string) []string {
+	drop := make(map[string]bool, len(names))
+	for _, name := range names {
+		drop[name] = true
+	}
+
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			// Flag parsing stops at the first non-flag argument
+			return append(kept, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		end := i + 1
+		if !hasValue && !isBoolFlag(fs, name) && end < len(args) {
+			end++
+		}
+		if !drop[name] {
+			kept = append(kept, args[i:end]...)
+		}
+		i = end - 1
+	}
+	return kept
+}
+
+func isBoolFlag(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
+)
+
+func TestRunDistributedMergesAgentStats(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer service.Close()
+
+	cfg := loadtest.LoadConfig{Requests: 10, Concurrency: 4}
+	base := []string{"--url=" + service.URL}
+	var agents []string
+	var plans [][]string
+	for i := 0; i < 3; i++ {
+		agent := httptest.NewServer(NewAgent().Handler())
+		defer agent.Close()
+		agents = append(agents, agent.URL)
+		plans = append(plans, agentArgs(base, cfg, 3, i))
+	}
+
+	stats, err := runDistributed(context.Background(), agents, plans, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.TotalRequests != 10 || stats.StatusCodes[200] != 10 {
+		t.Errorf("expected 10 requests with status 200, got %d (%v)", stats.TotalRequests, stats.StatusCodes)
+	}
+	if stats.Latency.Count() != 10 {
+		t.Errorf("expected 10 latency samples, got %d", stats.Latency.Count())
+	}
+}
+
+func TestRunDistributedReportsAgentErrors(t *testing.T) {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(agentMessage{Done: true, Error: "run failed"})
+	}))
+	defer agent.Close()
+
+	_, err := runDistributed(context.Background(), []string{agent.URL}, [][]string{nil}, nil)
+	if err == nil || !strings.Contains(err.Error(), "run failed") {
+		t.Errorf("expected the agent's error, got %v", err)
+	}
+}
+
+func TestAgentArgsSplitsLoad(t *testing.T) {
+	stages, _ := loadtest.ParseStages("10s:90")
+	cfg := loadtest.LoadConfig{Requests: 10, Concurrency: 2, Rate: 30, Stages: stages}
+
+	got := agentArgs([]string{"--url=http://x"}, cfg, 3, 0)
+	want := []string{"--url=http://x", "--requests=4", "--concurrency=1", "--rate=10", "--stages=10s:30", "--progress=0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWithoutFlags(t *testing.T) {
+	var opts Options
+	fs := newFlagSet("test", &opts)
+	fs.String("agents", "", "")
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"--agents", "a:1,b:2", "--url=http://x", "-agents=c:3", "--concurrency=2"},
+			[]string{"--url=http://x", "--concurrency=2"},
+		},
+		{
+			// Values equal to the flag name, or looking like flags, are kept
+			[]string{"--body", "agents", "-H", "--agents", "--insecure", "--url", "http://x", "--agents", "a:1"},
+			[]string{"--body", "agents", "-H", "--agents", "--insecure", "--url", "http://x"},
+		},
+		{
+			[]string{"--url=http://x", "--", "--agents", "a:1"},
+			[]string{"--url=http://x", "--", "--agents", "a:1"},
+		},
+	}
+	for _, tt := range tests {
+		if got := withoutFlags(fs, tt.args, "agents"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.args, tt.want, got)
+		}
+	}
+}
@@ -1,3 +1,22 @@
 module github.com/Leandroschwab/full-cycle-go/StressTest
 
-go 1.20
+go 1.23
+
+require (
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/gorilla/websocket v1.5.3
+	google.golang.org/grpc v1.72.0
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/sync v0.11.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+)
+
+require (
+	golang.org/x/net v0.35.0
+	golang.org/x/text v0.22.0 // indirect
+)
@@ -0,0 +1,142 @@
+package loadtest
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Assertion is a check applied to every response, written as kind=value:
+//
+//	status=200,201,2xx      status code, or class; for gRPC a code name such as OK
+//	body-contains=text      the body contains the text
+//	body-regex=^\{"ok"      the body matches the regular expression
+//	jsonpath=$.data.ok=true the value at a JSONPath equals the text
+//	header=X-Request-Id     the header (gRPC: response metadata) is present
+//	max-latency=500ms       the response arrived within the duration
+//
+// Responses that fail an assertion are not counted as successful.
+type Assertion struct {
+	Expression string
+	kind       string
+	statuses   []string
+	text       string
+	re         *regexp.Regexp
+	path       string
+	latency    time.Duration
+}
+
+// Assertions is the set of checks of a request; it collects repeatable
+// --assert flags.
+type Assertions []Assertion
+
+func (a *Assertions) String() string {
+	expressions := make([]string, len(*a))
+	for i, assertion := range *a {
+		expressions[i] = assertion.Expression
+	}
+	return strings.Join(expressions, ", ")
+}
+
+func (a *Assertions) Set(value string) error {
+	assertion, err := ParseAssertion(value)
+	if err != nil {
+		return err
+	}
+	*a = append(*a, assertion)
+	return nil
+}
+
+func ParseAssertion(expression string) (Assertion, error) {
+	kind, value, ok := strings.Cut(expression, "=")
+	if !ok || value == "" {
+		return Assertion{}, fmt.Errorf("invalid assertion %q, expected e.g. status=200", expression)
+	}
+	a := Assertion{Expression: expression, kind: strings.TrimSpace(kind)}
+	var err error
+	switch a.kind {
+	case "status":
+		for _, status := range strings.Split(value, ",") {
+			a.statuses = append(a.statuses, strings.TrimSpace(status))
+		}
+	case "body-contains":
+		a.text = value
+	case "body-regex":
+		a.re, err = regexp.Compile(value)
+	case "jsonpath":
+		var found bool
+		a.path, a.text, found = strings.Cut(value, "=")
+		if !found {
+			return Assertion{}, fmt.Errorf("invalid assertion %q, expected jsonpath=$.path=value", expression)
+		}
+		_, err = parseJSONPath(a.path)
+	case "header":
+		a.text = strings.TrimSpace(value)
+	case "max-latency":
+		a.latency, err = time.ParseDuration(value)
+	default:
+		return Assertion{}, fmt.Errorf("unknown assertion %q", a.kind)
+	}
+	if err != nil {
+		return Assertion{}, fmt.Errorf("invalid assertion %q: %w", expression, err)
+	}
+	return a, nil
+}
+
+// response is what assertions look at, for HTTP and gRPC alike.
+type response struct {
+	status   string
+	header   func(name string) bool
+	body     []byte
+	duration time.Duration
+}
+
+func (a Assertion) check(r response) bool {
+	switch a.kind {
+	case "status":
+		for _, status := range a.statuses {
+			if strings.EqualFold(status, r.status) ||
+				len(status) == 3 && strings.HasSuffix(strings.ToLower(status), "xx") && len(r.status) == 3 && status[0] == r.status[0] {
+				return true
+			}
+		}
+		return false
+	case "body-contains":
+		return bytes.Contains(r.body, []byte(a.text))
+	case "body-regex":
+		return a.re.Match(r.body)
+	case "jsonpath":
+		value, err := jsonPathString(r.body, a.path)
+		return err == nil && value == a.text
+	case "header":
+		return r.header(a.text)
+	case "max-latency":
+		return r.duration <= a.latency
+	}
+	return false
+}
+
+func (a Assertions) needsBody() bool {
+	for _, assertion := range a {
+		switch assertion.kind {
+		case "body-contains", "body-regex", "jsonpath":
+			return true
+		}
+	}
+	return false
+}
+
+// apply checks the response and records the outcome on the result.
+func (a Assertions) apply(result *Result, r response) {
+	if len(a) == 0 {
+		return
+	}
+	result.Asserted = true
+	for _, assertion := range a {
+		if !assertion.check(r) {
+			result.AssertionFailures = append(result.AssertionFailures, assertion.Expression)
+		}
+	}
+}
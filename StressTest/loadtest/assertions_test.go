@@ -0,0 +1,77 @@
+package loadtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAssertions(t *testing.T) {
+	r := response{
+		status:   "201",
+		header:   func(name string) bool { return name == "X-Request-Id" },
+		body:     []byte(`{"data":{"ok":true,"id":7},"msg":"created"}`),
+		duration: 200 * time.Millisecond,
+	}
+	cases := map[string]bool{
+		"status=200,201":          true,
+		"status=2xx":              true,
+		"status=200":              false,
+		"body-contains=created":   true,
+		"body-contains=error":     false,
+		`body-regex="id":\d+`:     true,
+		"jsonpath=$.data.ok=true": true,
+		"jsonpath=$.data.id=8":    false,
+		"jsonpath=$.missing=x":    false,
+		"header=X-Request-Id":     true,
+		"header=X-Trace":          false,
+		"max-latency=250ms":       true,
+		"max-latency=100ms":       false,
+	}
+	for expression, want := range cases {
+		a, err := ParseAssertion(expression)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", expression, err)
+			continue
+		}
+		if got := a.check(r); got != want {
+			t.Errorf("%s: expected %v, got %v", expression, want, got)
+		}
+	}
+
+	for _, invalid := range []string{"status", "unknown=1", "body-regex=(", "jsonpath=$.a", "max-latency=fast"} {
+		if _, err := ParseAssertion(invalid); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestFailedAssertionsAreNotSuccessful(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("broken") != "" {
+			w.Write([]byte(`{"error":"database down"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	var assertions Assertions
+	assertions.Set("status=200")
+	assertions.Set("jsonpath=$.status=ok")
+	stats := NewStats()
+	for _, query := range []string{"", "?broken=1", ""} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+query, nil)
+		result, _ := doRequest(server.Client(), req, time.Now(), false, assertions)
+		stats.Add(result)
+	}
+
+	if stats.StatusCodes[200] != 3 || stats.SuccessCount != 2 || stats.AssertionFailed != 1 {
+		t.Errorf("expected 3 responses with status 200 of which 2 successful, got %d, %d and %d failed assertions",
+			stats.StatusCodes[200], stats.SuccessCount, stats.AssertionFailed)
+	}
+	if stats.AssertionFailures["jsonpath=$.status=ok"] != 1 {
+		t.Errorf("expected the jsonpath assertion to fail once, got %v", stats.AssertionFailures)
+	}
+}
@@ -0,0 +1,248 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// MinThroughput is the share of the target rate a capacity step must
+// achieve: below it the workers could not keep up, so the step fails even
+// if the latency SLO holds.
+const MinThroughput = 0.9
+
+// CapacitySearch looks for the highest request rate a target sustains
+// within an SLO. It raises the rate by Step from Start until a step breaks
+// the SLO (or Max is reached), then binary searches between the last
+// passing and the first failing rate until they are within Precision of
+// each other. When even Start breaks the SLO there is nothing to search and
+// the result has no knee.
+type CapacitySearch struct {
+	Target Target
+	Client *http.Client
+
+	// Concurrency caps the requests in flight during each step
+	Concurrency int
+
+	Start, Step float64
+	// Max is the highest rate tried; 0 means no limit
+	Max float64
+
+	// StepDuration is how long each rate is held
+	StepDuration time.Duration
+
+	// Precision is the relative gap between passing and failing rates at
+	// which the search stops, e.g. 0.05
+	Precision float64
+
+	// SLO holds the conditions every step must meet
+	SLO []Threshold
+
+	// OnStepStart is called before each step; OnResult for every result and
+	// OnStep with the outcome of each step
+	OnStepStart func(cfg LoadConfig, start time.Time)
+	OnResult    func(Result)
+	OnStep      func(CapacityStep)
+}
+
+// CapacityStep is the outcome of the load held at one rate.
+type CapacityStep struct {
+	// Phase is "ramp" while increasing the rate and "search" afterwards
+	Phase      string
+	Rate       float64
+	Report     *Report
+	Thresholds []ThresholdResult
+	Passed     bool
+}
+
+// CapacityResult is the outcome of a capacity search.
+type CapacityResult struct {
+	Steps []CapacityStep
+
+	// Knee is the highest rate that met the SLO, 0 when none did
+	Knee float64
+	// Limit is the lowest rate that broke it, 0 when none did
+	Limit       float64
+	Interrupted bool
+}
+
+func (s *CapacitySearch) validate() error {
+	switch {
+	case s.Target == nil:
+		return fmt.Errorf("a target is required")
+	case s.Concurrency <= 0:
+		return fmt.Errorf("concurrency must be greater than zero")
+	case s.Start <= 0 || s.Step <= 0:
+		return fmt.Errorf("the start and step rates must be greater than zero")
+	case s.Max < 0:
+		return fmt.Errorf("the maximum rate must not be negative")
+	case s.Max > 0 && s.Max < s.Start:
+		return fmt.Errorf("the maximum rate must not be below the start rate")
+	case s.StepDuration <= 0:
+		return fmt.Errorf("the step duration must be greater than zero")
+	case s.Precision <= 0 || s.Precision >= 1:
+		return fmt.Errorf("the precision must be between 0 and 100%%")
+	case len(s.SLO) == 0:
+		return fmt.Errorf("at least one SLO threshold is required")
+	}
+	return nil
+}
+
+// Run executes the search. Canceling ctx stops it after draining the step
+// in progress; the steps finished so far are returned.
+func (s *CapacitySearch) Run(ctx context.Context) (*CapacityResult, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	result := &CapacityResult{}
+	run := func(phase string, rate float64) (bool, error) {
+		step, err := s.runStep(ctx, phase, rate)
+		if err != nil {
+			return false, err
+		}
+		if step.Report.Interrupted {
+			result.Interrupted = true
+			return false, nil
+		}
+		result.Steps = append(result.Steps, step)
+		if s.OnStep != nil {
+			s.OnStep(step)
+		}
+		if step.Passed {
+			result.Knee = rate
+		} else {
+			result.Limit = rate
+		}
+		return true, nil
+	}
+
+	// Ramp up until the SLO breaks
+	for rate := s.Start; result.Limit == 0; rate += s.Step {
+		if s.Max > 0 && rate > s.Max {
+			if result.Knee == s.Max {
+				break
+			}
+			rate = s.Max
+		}
+		if ok, err := run("ramp", rate); err != nil || !ok {
+			return result, err
+		}
+	}
+
+	if result.Knee == 0 {
+		return result, nil
+	}
+
+	// Narrow the gap between the knee and the limit
+	for result.Limit > 0 && result.Limit-result.Knee > s.Precision*result.Limit {
+		if ok, err := run("search", (result.Knee+result.Limit)/2); err != nil || !ok {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (s *CapacitySearch) runStep(ctx context.Context, phase string, rate float64) (CapacityStep, error) {
+	cfg := LoadConfig{Concurrency: s.Concurrency, Duration: s.StepDuration, Rate: rate}
+	if s.OnStepStart != nil {
+		s.OnStepStart(cfg, time.Now())
+	}
+	runner := Runner{Target: s.Target, Config: cfg, Client: s.Client, OnResult: s.OnResult}
+	report, err := runner.Run(ctx)
+	if err != nil {
+		return CapacityStep{}, err
+	}
+	thresholds := report.Check(s.SLO)
+	achieved := report.RequestsPerSecond()
+	return CapacityStep{
+		Phase:      phase,
+		Rate:       rate,
+		Report:     report,
+		Thresholds: thresholds,
+		Passed:     ThresholdsPassed(thresholds) && achieved >= MinThroughput*rate,
+	}, nil
+}
+
+// PrintCapacity writes the latency curve of the steps, ordered by rate, and
+// the knee point.
+func PrintCapacity(w io.Writer, result *CapacityResult) {
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintln(w, "Capacity Report")
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintf(w, "%10s %10s %10s %10s %10s %8s  %s\n", "Rate", "Achieved", "p50", "p95", "p99", "Errors", "SLO")
+	for _, step := range result.sortedSteps() {
+		stats := step.Report.Stats
+		verdict := "PASS"
+		if !step.Passed {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(w, "%10.2f %10.2f %10v %10v %10v %7.2f%%  %s\n",
+			step.Rate, step.Report.RequestsPerSecond(),
+			round(stats.Latency.Percentile(50)), round(stats.Latency.Percentile(95)), round(stats.Latency.Percentile(99)),
+			stats.ratio("error_rate")*100, verdict)
+	}
+	fmt.Fprintln(w, "--------------------------------------------------")
+	switch {
+	case result.Knee == 0:
+		fmt.Fprintln(w, "No rate met the SLO")
+	case result.Limit == 0:
+		fmt.Fprintf(w, "Maximum sustainable rate: at least %.2f rps (no tested rate broke the SLO)\n", result.Knee)
+	default:
+		fmt.Fprintf(w, "Maximum sustainable rate: %.2f rps (SLO broken at %.2f rps)\n", result.Knee, result.Limit)
+	}
+	fmt.Fprintln(w, "--------------------------------------------------")
+}
+
+func (r *CapacityResult) sortedSteps() []CapacityStep {
+	steps := append([]CapacityStep(nil), r.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Rate < steps[j].Rate })
+	return steps
+}
+
+// CapacitySummary is the machine-readable form of a capacity search.
+type CapacitySummary struct {
+	StartedAt   time.Time             `json:"started_at"`
+	KneeRPS     float64               `json:"knee_rps"`
+	LimitRPS    float64               `json:"limit_rps,omitempty"`
+	Interrupted bool                  `json:"interrupted,omitempty"`
+	Steps       []CapacityStepSummary `json:"steps"`
+}
+
+// CapacityStepSummary is one point of the latency curve.
+type CapacityStepSummary struct {
+	Phase             string            `json:"phase"`
+	TargetRate        float64           `json:"target_rps"`
+	RequestsPerSecond float64           `json:"requests_per_second"`
+	TotalRequests     int               `json:"total_requests"`
+	ErrorRate         float64           `json:"error_rate"`
+	Latency           LatencySummary    `json:"latency"`
+	Thresholds        []ThresholdResult `json:"thresholds"`
+	Passed            bool              `json:"passed"`
+}
+
+// Summary is the machine-readable form of the result, steps in the order
+// they ran.
+func (r *CapacityResult) Summary() CapacitySummary {
+	summary := CapacitySummary{KneeRPS: r.Knee, LimitRPS: r.Limit, Interrupted: r.Interrupted}
+	for i, step := range r.Steps {
+		if i == 0 {
+			summary.StartedAt = step.Report.StartedAt
+		}
+		latency := newLatencySummary(step.Report.Stats.Latency)
+		latency.Histogram = nil
+		summary.Steps = append(summary.Steps, CapacityStepSummary{
+			Phase:             step.Phase,
+			TargetRate:        step.Rate,
+			RequestsPerSecond: step.Report.RequestsPerSecond(),
+			TotalRequests:     step.Report.Stats.TotalRequests,
+			ErrorRate:         step.Report.Stats.ratio("error_rate"),
+			Latency:           latency,
+			Thresholds:        step.Thresholds,
+			Passed:            step.Passed,
+		})
+	}
+	return summary
+}
@@ -0,0 +1,123 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapacitySearchFindsKnee(t *testing.T) {
+	// Two workers and 20ms per request saturate at about 100 rps
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	target, _ := NewRequestTemplate(RequestSpec{Method: http.MethodGet, URL: server.URL})
+	slo, _ := ParseThreshold("error_rate<1%")
+	var started int
+	search := CapacitySearch{
+		Target:       target,
+		Concurrency:  2,
+		Start:        20,
+		Step:         40,
+		StepDuration: 500 * time.Millisecond,
+		Precision:    0.2,
+		SLO:          []Threshold{slo},
+		OnStepStart:  func(LoadConfig, time.Time) { started++ },
+	}
+	result, err := search.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Knee < 20 || result.Knee > 110 {
+		t.Errorf("expected the knee between 20 and 110 rps, got %g", result.Knee)
+	}
+	if result.Limit <= result.Knee || result.Limit-result.Knee > 0.2*result.Limit {
+		t.Errorf("expected the limit within 20%% above the knee, got knee %g limit %g", result.Knee, result.Limit)
+	}
+	if started != len(result.Steps) || result.Steps[0].Phase != "ramp" || result.Steps[0].Rate != 20 {
+		t.Errorf("unexpected steps %+v", result.Steps)
+	}
+
+	var out strings.Builder
+	PrintCapacity(&out, result)
+	if !strings.Contains(out.String(), "Maximum sustainable rate") {
+		t.Errorf("expected the knee in the report, got:\n%s", out.String())
+	}
+	if summary := result.Summary(); len(summary.Steps) != len(result.Steps) || summary.KneeRPS != result.Knee {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestCapacitySearchStopsAtMax(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	target, _ := NewRequestTemplate(RequestSpec{Method: http.MethodGet, URL: server.URL})
+	slo, _ := ParseThreshold("error_rate<1%")
+	search := CapacitySearch{
+		Target:       target,
+		Concurrency:  4,
+		Start:        10,
+		Step:         15,
+		Max:          30,
+		StepDuration: 200 * time.Millisecond,
+		Precision:    0.1,
+		SLO:          []Threshold{slo},
+	}
+	result, err := search.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rates []float64
+	for _, step := range result.Steps {
+		rates = append(rates, step.Rate)
+	}
+	if len(rates) != 3 || rates[2] != 30 || result.Knee != 30 || result.Limit != 0 {
+		t.Errorf("expected steps at 10, 25 and 30 rps passing, got %v (knee %g, limit %g)", rates, result.Knee, result.Limit)
+	}
+
+	search.SLO = nil
+	if _, err := search.Run(context.Background()); err == nil {
+		t.Error("expected an error for a search without an SLO")
+	}
+}
+
+func TestCapacitySearchStopsWhenStartBreaksSLO(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	target, _ := NewRequestTemplate(RequestSpec{Method: http.MethodGet, URL: server.URL})
+	slo, _ := ParseThreshold("error_rate<1%")
+	search := CapacitySearch{
+		Target:       target,
+		Concurrency:  2,
+		Start:        20,
+		Step:         20,
+		StepDuration: 200 * time.Millisecond,
+		Precision:    0.1,
+		SLO:          []Threshold{slo},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := search.Run(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Interrupted || len(result.Steps) != 1 || result.Knee != 0 || result.Limit != 20 {
+		t.Errorf("expected a single failing step at 20 rps, got %d steps (knee %g, limit %g, interrupted %v)",
+			len(result.Steps), result.Knee, result.Limit, result.Interrupted)
+	}
+
+	var out strings.Builder
+	PrintCapacity(&out, result)
+	if !strings.Contains(out.String(), "No rate met the SLO") {
+		t.Errorf("expected no knee in the report, got:\n%s", out.String())
+	}
+}
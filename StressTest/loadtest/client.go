@@ -0,0 +1,187 @@
+package loadtest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// ClientConfig holds the HTTP client settings shared by all workers.
+type ClientConfig struct {
+	// Timeout bounds the whole request, including reading the body
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	TLSTimeout     time.Duration
+
+	// Protocol is "" (negotiated), "1.1", "2" (over TLS) or "h2c"
+	Protocol          string
+	DisableKeepAlives bool
+	// MaxConnsPerHost caps the connections to each host, idle or not; 0
+	// means no limit
+	MaxConnsPerHost    int
+	InsecureSkipVerify bool
+	CertFile           string
+	KeyFile            string
+}
+
+func NewHTTPClient(cfg ClientConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	// Keep every connection the workers opened for reuse: with net/http's
+	// default of two idle connections per host, all but two are closed and
+	// redialed whenever the workers wait, e.g. between paced requests
+	idleConnsPerHost := cfg.MaxConnsPerHost
+	if idleConnsPerHost == 0 {
+		idleConnsPerHost = math.MaxInt
+	}
+
+	// HTTP/2 multiplexes every request over one long-lived connection per
+	// host, so these settings have no meaning there
+	if (cfg.Protocol == "2" || cfg.Protocol == "h2c") && (cfg.DisableKeepAlives || cfg.MaxConnsPerHost > 0) {
+		return nil, fmt.Errorf("disabling keep-alive and limiting connections per host are not supported over HTTP/2")
+	}
+
+	var transport http.RoundTripper
+	switch cfg.Protocol {
+	case "", "1.1":
+		t := &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   cfg.TLSTimeout,
+			DisableKeepAlives:     cfg.DisableKeepAlives,
+			MaxConnsPerHost:       cfg.MaxConnsPerHost,
+			MaxIdleConnsPerHost:   idleConnsPerHost,
+			IdleConnTimeout:       90 * time.Second,
+			ExpectContinueTimeout: time.Second,
+			ForceAttemptHTTP2:     cfg.Protocol == "",
+		}
+		if cfg.Protocol == "1.1" {
+			// A non-nil empty map disables the HTTP/2 upgrade
+			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+		transport = t
+	case "2":
+		transport = &http2.Transport{
+			TLSClientConfig: tlsConfig,
+			DialTLSContext: func(ctx context.Context, network, addr string, c *tls.Config) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				if cfg.TLSTimeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, cfg.TLSTimeout)
+					defer cancel()
+				}
+				tlsConn := tls.Client(conn, c)
+				if err := tlsConn.HandshakeContext(ctx); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return tlsConn, nil
+			},
+		}
+	case "h2c":
+		// HTTP/2 over cleartext TCP, with prior knowledge of server support
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unknown protocol %q (expected 1.1, 2 or h2c)", cfg.Protocol)
+	}
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+	}, nil
+}
+
+// ConnTrace holds the connection phase timings of one request. Phases that
+// did not happen, such as DNS on a reused connection, stay zero.
+type ConnTrace struct {
+	NewConn bool
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+}
+
+// connTracer collects ConnTrace timings through httptrace. Dial callbacks
+// can fire on other goroutines, hence the mutex.
+type connTracer struct {
+	mu                               sync.Mutex
+	start                            time.Time
+	dnsStart, connectStart, tlsStart time.Time
+	trace                            ConnTrace
+}
+
+func withConnTrace(req *http.Request) (*http.Request, *connTracer) {
+	t := &connTracer{start: time.Now()}
+	clientTrace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.since(&t.dnsStart, &t.trace.DNS)
+		},
+		ConnectStart: func(string, string) { t.mark(&t.connectStart) },
+		ConnectDone: func(string, string, error) {
+			t.since(&t.connectStart, &t.trace.Connect)
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(&t.tlsStart, &t.trace.TLS)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.trace.NewConn = !info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.since(&t.start, &t.trace.TTFB)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), clientTrace)), t
+}
+
+func (t *connTracer) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *connTracer) since(start *time.Time, d *time.Duration) {
+	t.mu.Lock()
+	if !start.IsZero() {
+		*d = time.Since(*start)
+	}
+	t.mu.Unlock()
+}
+
+func (t *connTracer) result() ConnTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.trace
+}
@@ -0,0 +1,83 @@
+package loadtest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientKeepsIdleConnectionsOfAllWorkers(t *testing.T) {
+	var newConns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := NewHTTPClient(ClientConfig{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Two bursts of 20 concurrent requests with every connection idle in
+	// between, as with a paced rate
+	for round := 0; round < 2; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := client.Get(server.URL)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}()
+		}
+		wg.Wait()
+	}
+
+	if n := newConns.Load(); n > 20 {
+		t.Errorf("expected the second burst to reuse the 20 idle connections, got %d connections", n)
+	}
+}
+
+func TestHTTP2ClientSettings(t *testing.T) {
+	for _, cfg := range []ClientConfig{
+		{Protocol: "2", DisableKeepAlives: true},
+		{Protocol: "h2c", MaxConnsPerHost: 4},
+	} {
+		if _, err := NewHTTPClient(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+
+	// A server that never answers the TLS handshake
+	silent := rawServerURL(t, func(conn *net.TCPConn) {
+		t.Cleanup(func() { conn.Close() })
+	})
+	client, err := NewHTTPClient(ClientConfig{Protocol: "2", Timeout: 5 * time.Second, TLSTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	if _, err := client.Get(strings.Replace(silent, "http://", "https://", 1)); err == nil {
+		t.Fatal("expected the handshake to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the TLS timeout to end the handshake, took %s", elapsed)
+	}
+}
@@ -0,0 +1,191 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// CompareTolerances bounds how much worse a candidate run may be than the
+// baseline before a metric counts as a regression.
+type CompareTolerances struct {
+	Latency   float64 // relative increase of a latency metric
+	RPS       float64 // relative decrease of the throughput
+	ErrorRate float64 // absolute increase of the error rate
+	Alpha     float64 // significance level of the latency distribution test
+}
+
+// MetricComparison is one row of a comparison. Change is relative to the
+// baseline, except for the error rate where it is the absolute difference.
+type MetricComparison struct {
+	Name       string
+	Baseline   float64
+	Candidate  float64
+	Change     float64
+	Regression bool
+	latency    bool
+}
+
+// MannWhitney is the outcome of a Mann-Whitney U test on two latency
+// distributions. ProbSlower is the chance that a random candidate request is
+// slower than a random baseline one (0.5 means no difference).
+type MannWhitney struct {
+	U          float64
+	Z          float64
+	P          float64
+	ProbSlower float64
+}
+
+type Comparison struct {
+	Metrics     []MetricComparison
+	MannWhitney *MannWhitney
+}
+
+func (c Comparison) Regressions() int {
+	n := 0
+	for _, m := range c.Metrics {
+		if m.Regression {
+			n++
+		}
+	}
+	return n
+}
+
+// CompareSummaries compares two JSON reports. A latency metric is a
+// regression only when it grew beyond the tolerance and, if both reports
+// carry their histograms, the distributions differ significantly.
+func CompareSummaries(baseline, candidate Summary, tol CompareTolerances) Comparison {
+	var c Comparison
+	if baseline.Latency.Histogram != nil && candidate.Latency.Histogram != nil {
+		c.MannWhitney = mannWhitney(baseline.Latency.Histogram, candidate.Latency.Histogram)
+	}
+	significant := c.MannWhitney == nil || c.MannWhitney.P < tol.Alpha
+
+	addLatency := func(name string, base, cand float64) {
+		m := MetricComparison{Name: name, Baseline: base, Candidate: cand, Change: relativeChange(base, cand), latency: true}
+		m.Regression = significant && m.Change > tol.Latency
+		c.Metrics = append(c.Metrics, m)
+	}
+	addLatency("mean", baseline.Latency.MeanMs, candidate.Latency.MeanMs)
+	for _, p := range reportPercentiles {
+		name := percentileName(p)
+		addLatency(name, baseline.Latency.Percentiles[name], candidate.Latency.Percentiles[name])
+	}
+
+	rps := MetricComparison{
+		Name:      "rps",
+		Baseline:  baseline.RequestsPerSecond,
+		Candidate: candidate.RequestsPerSecond,
+		Change:    relativeChange(baseline.RequestsPerSecond, candidate.RequestsPerSecond),
+	}
+	rps.Regression = -rps.Change > tol.RPS
+	c.Metrics = append(c.Metrics, rps)
+
+	errorRate := MetricComparison{
+		Name:      "error_rate",
+		Baseline:  baseline.ErrorRate,
+		Candidate: candidate.ErrorRate,
+		Change:    candidate.ErrorRate - baseline.ErrorRate,
+	}
+	errorRate.Regression = errorRate.Change > tol.ErrorRate
+	c.Metrics = append(c.Metrics, errorRate)
+	return c
+}
+
+func relativeChange(base, cand float64) float64 {
+	if base == 0 {
+		if cand == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (cand - base) / base
+}
+
+// mannWhitney runs the U test on two histograms. Samples in the same bucket
+// are treated as ties, which the normal approximation corrects for.
+func mannWhitney(baseline, candidate *Histogram) *MannWhitney {
+	n1, n2 := float64(baseline.total), float64(candidate.total)
+	if n1 == 0 || n2 == 0 {
+		return nil
+	}
+	n := n1 + n2
+
+	var rankSum, tieSum, seen float64
+	for idx := range baseline.counts {
+		a, b := float64(baseline.counts[idx]), float64(candidate.counts[idx])
+		t := a + b
+		if t == 0 {
+			continue
+		}
+		rankSum += b * (seen + (t+1)/2)
+		tieSum += t*t*t - t
+		seen += t
+	}
+	u := rankSum - n2*(n2+1)/2
+
+	result := &MannWhitney{U: u, P: 1, ProbSlower: u / (n1 * n2)}
+	variance := n1 * n2 / 12 * ((n + 1) - tieSum/(n*(n-1)))
+	if variance > 0 {
+		result.Z = (u - n1*n2/2) / math.Sqrt(variance)
+		result.P = math.Erfc(math.Abs(result.Z) / math.Sqrt2)
+	}
+	return result
+}
+
+func PrintComparison(w io.Writer, c Comparison, tol CompareTolerances) {
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintf(w, "%-12s %12s %12s %10s\n", "Metric", "Baseline", "Candidate", "Change")
+	for _, m := range c.Metrics {
+		var base, cand, change string
+		switch {
+		case m.latency:
+			base, cand = formatMs(m.Baseline), formatMs(m.Candidate)
+			change = formatPercent(m.Change)
+		case m.Name == "error_rate":
+			base = strconv.FormatFloat(m.Baseline*100, 'f', 2, 64) + "%"
+			cand = strconv.FormatFloat(m.Candidate*100, 'f', 2, 64) + "%"
+			change = fmt.Sprintf("%+.2fpp", m.Change*100)
+		default:
+			base = strconv.FormatFloat(m.Baseline, 'f', 2, 64)
+			cand = strconv.FormatFloat(m.Candidate, 'f', 2, 64)
+			change = formatPercent(m.Change)
+		}
+		line := fmt.Sprintf("%-12s %12s %12s %10s", m.Name, base, cand, change)
+		if m.Regression {
+			line += "  REGRESSION"
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w, "--------------------------------------------------")
+
+	if mw := c.MannWhitney; mw != nil {
+		verdict := "not significant"
+		if mw.P < tol.Alpha {
+			verdict = "significant"
+		}
+		fmt.Fprintf(w, "Latency distribution (Mann-Whitney U): p=%.4g, %s at alpha %g\n", mw.P, verdict, tol.Alpha)
+		fmt.Fprintf(w, "A candidate request is slower than a baseline one %.1f%% of the time\n", mw.ProbSlower*100)
+	} else {
+		fmt.Fprintln(w, "Latency distribution test skipped: a report has no latency histogram")
+	}
+
+	if n := c.Regressions(); n > 0 {
+		fmt.Fprintf(w, "Regressions: %d\n", n)
+	} else {
+		fmt.Fprintln(w, "No regressions")
+	}
+}
+
+func formatMs(ms float64) string {
+	return round(time.Duration(ms * float64(time.Millisecond))).String()
+}
+
+func formatPercent(change float64) string {
+	if math.IsInf(change, 1) {
+		return "+inf"
+	}
+	return fmt.Sprintf("%+.1f%%", change*100)
+}
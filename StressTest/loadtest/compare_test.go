@@ -0,0 +1,58 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+func histogramOf(latencies ...time.Duration) *Histogram {
+	h := NewHistogram()
+	for _, d := range latencies {
+		h.Record(d)
+	}
+	return h
+}
+
+func TestMannWhitney(t *testing.T) {
+	var fast, slow []time.Duration
+	for i := 0; i < 200; i++ {
+		fast = append(fast, time.Duration(10+i%20)*time.Millisecond)
+		slow = append(slow, time.Duration(15+i%20)*time.Millisecond)
+	}
+
+	same := mannWhitney(histogramOf(fast...), histogramOf(fast...))
+	if same.P < 0.99 || same.ProbSlower != 0.5 {
+		t.Errorf("identical samples: expected p~1 and 0.5, got p=%v prob=%v", same.P, same.ProbSlower)
+	}
+	shifted := mannWhitney(histogramOf(fast...), histogramOf(slow...))
+	if shifted.P > 0.001 || shifted.ProbSlower <= 0.5 {
+		t.Errorf("shifted samples: expected significant slowdown, got p=%v prob=%v", shifted.P, shifted.ProbSlower)
+	}
+}
+
+func TestCompareSummariesFlagsRegressions(t *testing.T) {
+	baselineStats, candidateStats := NewStats(), NewStats()
+	for i := 0; i < 500; i++ {
+		baselineStats.Add(Result{StatusCode: 200, Duration: time.Duration(10+i%10) * time.Millisecond})
+		candidateStats.Add(Result{StatusCode: 200, Duration: time.Duration(20+i%10) * time.Millisecond})
+	}
+	candidateStats.Add(Result{StatusCode: 500, Duration: 20 * time.Millisecond})
+	baseline := newSummary(LoadConfig{}, time.Time{}, time.Second, baselineStats)
+	candidate := newSummary(LoadConfig{}, time.Time{}, time.Second, candidateStats)
+
+	tol := CompareTolerances{Latency: 0.1, RPS: 0.1, ErrorRate: 0.01, Alpha: 0.05}
+	regressions := map[string]bool{}
+	for _, m := range CompareSummaries(baseline, candidate, tol).Metrics {
+		regressions[m.Name] = m.Regression
+	}
+	if !regressions["p50"] || !regressions["mean"] {
+		t.Errorf("expected latency regressions, got %v", regressions)
+	}
+	if regressions["rps"] || regressions["error_rate"] {
+		t.Errorf("expected rps and error rate within tolerance, got %v", regressions)
+	}
+
+	if n := CompareSummaries(baseline, baseline, tol).Regressions(); n != 0 {
+		t.Errorf("expected no regressions comparing a run with itself, got %d", n)
+	}
+}
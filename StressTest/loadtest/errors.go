@@ -0,0 +1,124 @@
+package loadtest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Error classes of requests that got no response.
+const (
+	ErrClassDNS      = "dns"
+	ErrClassRefused  = "connection_refused"
+	ErrClassReset    = "connection_reset"
+	ErrClassTimeout  = "timeout"
+	ErrClassTLS      = "tls"
+	ErrClassCanceled = "canceled"
+	ErrClassClosed   = "connection_closed"
+	ErrClassOther    = "other"
+)
+
+// ErrClassAssertion groups responses that failed an assertion but would
+// otherwise look healthy.
+const ErrClassAssertion = "assertion"
+
+const (
+	maxErrorSamples     = 3
+	maxErrorSampleBytes = 200
+)
+
+// classifyError maps a transport error to a class that tells a saturated
+// server (timeouts, resets) from a broken network or setup (DNS, refused,
+// TLS).
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrClassCanceled
+	case errors.As(err, &dnsErr):
+		return ErrClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrClassRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrClassReset
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrClassTimeout
+	case errors.As(err, &recordErr), errors.As(err, &certErr), errors.As(err, &unknownAuthority),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert), strings.Contains(err.Error(), "tls:"),
+		strings.Contains(err.Error(), "HTTP response to HTTPS client"):
+		return ErrClassTLS
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrClassClosed
+	}
+	return ErrClassOther
+}
+
+// errorClass groups a result for progress and error reporting, returning an
+// empty string for a successful response.
+func errorClass(result Result) string {
+	switch {
+	case result.Error != nil:
+		return classifyError(result.Error)
+	case result.Proto == "grpc":
+		if result.GRPCCode != codes.OK {
+			return "grpc_" + result.GRPCCode.String()
+		}
+	case result.StatusCode >= 500:
+		return "5xx"
+	case result.StatusCode >= 400:
+		return "4xx"
+	}
+	if len(result.AssertionFailures) > 0 {
+		return ErrClassAssertion
+	}
+	return ""
+}
+
+// ErrorClassStats counts the failures of one class and keeps a few distinct
+// messages as samples.
+type ErrorClassStats struct {
+	Count   int      `json:"count"`
+	Samples []string `json:"samples"`
+}
+
+func (e *ErrorClassStats) add(err error) {
+	e.Count++
+	msg := err.Error()
+	if len(msg) > maxErrorSampleBytes {
+		msg = msg[:maxErrorSampleBytes] + "..."
+	}
+	e.addSample(msg)
+}
+
+func (e *ErrorClassStats) merge(other *ErrorClassStats) {
+	e.Count += other.Count
+	for _, sample := range other.Samples {
+		e.addSample(sample)
+	}
+}
+
+func (e *ErrorClassStats) addSample(msg string) {
+	if len(e.Samples) >= maxErrorSamples {
+		return
+	}
+	for _, sample := range e.Samples {
+		if sample == msg {
+			return
+		}
+	}
+	e.Samples = append(e.Samples, msg)
+}
@@ -0,0 +1,109 @@
+package loadtest
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// closedPortURL returns the URL of a port nothing listens on any more.
+func closedPortURL(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return "http://" + addr
+}
+
+// rawServerURL serves every connection with handle, which reads the request
+// line and then misbehaves.
+func rawServerURL(t *testing.T, handle func(conn *net.TCPConn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Read(make([]byte, 1024))
+			handle(conn.(*net.TCPConn))
+		}
+	}()
+	return "http://" + listener.Addr().String()
+}
+
+func TestClassifyErrorOfRealFailures(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer slow.Close()
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	reset := rawServerURL(t, func(conn *net.TCPConn) {
+		// Closing with a zero linger sends a RST instead of a FIN
+		conn.SetLinger(0)
+		conn.Close()
+	})
+	closed := rawServerURL(t, func(conn *net.TCPConn) {
+		conn.Close()
+	})
+
+	tests := []struct {
+		name    string
+		url     string
+		timeout time.Duration
+		want    string
+	}{
+		{"refused", closedPortURL(t), 0, ErrClassRefused},
+		{"dns", "http://stress-test.invalid", 0, ErrClassDNS},
+		{"client timeout", slow.URL, 50 * time.Millisecond, ErrClassTimeout},
+		{"tls", tlsServer.URL, 0, ErrClassTLS},
+		{"reset", reset, 0, ErrClassReset},
+		{"closed", closed, 0, ErrClassClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{
+				Timeout:   tt.timeout,
+				Transport: &http.Transport{DisableKeepAlives: true},
+			}
+			resp, err := client.Get(tt.url)
+			if err == nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				t.Fatal("expected the request to fail")
+			}
+			if got := classifyError(err); got != tt.want {
+				t.Errorf("expected %s, got %s for %v", tt.want, got, err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, slow.URL, nil)
+	if _, err := http.DefaultClient.Do(req); classifyError(err) != ErrClassTimeout {
+		t.Errorf("expected a context deadline to be a timeout, got %s for %v", classifyError(err), err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, slow.URL, nil)
+	if _, err := http.DefaultClient.Do(req); classifyError(err) != ErrClassCanceled {
+		t.Errorf("expected a canceled request to be canceled, got %s for %v", classifyError(err), err)
+	}
+}
@@ -0,0 +1,432 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exportQuantiles are the latency quantiles exported for every interval.
+var exportQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// MetricsExporter pushes live run metrics to a Prometheus pushgateway and/or
+// an OTLP/HTTP collector every interval, and once more when the run ends.
+// Counters are cumulative since the start of the run; the request rate and
+// latency quantiles cover the interval since the previous push.
+type MetricsExporter struct {
+	mu           sync.Mutex
+	client       *http.Client
+	pushgateway  string
+	otlp         string
+	target       string
+	run          string
+	start        time.Time
+	lastPush     time.Time
+	requests     int
+	errors       map[string]int
+	latencySum   time.Duration
+	latencyCount int64
+	window       *Histogram
+	windowN      int
+
+	pushes   int
+	failures int
+	firstErr error
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// metricsSnapshot is what one push sends.
+type metricsSnapshot struct {
+	target       string
+	run          string
+	start        time.Time
+	at           time.Time
+	requests     int
+	errors       map[string]int
+	rps          float64
+	latencySum   time.Duration
+	latencyCount int64
+	quantiles    map[float64]time.Duration // empty without responses in the interval
+}
+
+func NewMetricsExporter(pushgateway, otlp, target string, start time.Time) *MetricsExporter {
+	return &MetricsExporter{
+		client:      &http.Client{Timeout: 5 * time.Second},
+		pushgateway: pushgatewayURL(pushgateway, start),
+		otlp:        otlpURL(otlp),
+		target:      target,
+		run:         runID(start),
+		start:       start,
+		lastPush:    start,
+		errors:      make(map[string]int),
+		window:      NewHistogram(),
+	}
+}
+
+// runID tells the pushes of different runs apart.
+func runID(start time.Time) string {
+	return start.UTC().Format("20060102T150405Z")
+}
+
+// pushgatewayURL adds the grouping key unless the URL already has one.
+func pushgatewayURL(base string, start time.Time) string {
+	if base == "" || strings.Contains(base, "/metrics/job/") {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/metrics/job/stress_test/run/" + runID(start)
+}
+
+// otlpURL adds the standard metrics path to an endpoint given without one.
+func otlpURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if endpoint == "" || err != nil || (u.Path != "" && u.Path != "/") {
+		return endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/v1/metrics"
+}
+
+func (e *MetricsExporter) Add(result Result) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.windowN++
+	if class := errorClass(result); class != "" {
+		e.errors[class]++
+	}
+	if result.Error == nil {
+		e.window.Record(result.Duration)
+		e.latencySum += result.Duration
+		e.latencyCount++
+	}
+}
+
+// AddStats counts a batch of aggregated results streamed by distributed
+// agents.
+func (e *MetricsExporter) AddStats(delta *Stats) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests += delta.TotalRequests
+	e.windowN += delta.TotalRequests
+	for class, count := range delta.errorCounts() {
+		e.errors[class] += count
+	}
+	e.window.Merge(delta.Latency)
+	e.latencySum += delta.Latency.Mean() * time.Duration(delta.Latency.Count())
+	e.latencyCount += delta.Latency.Count()
+}
+
+// Start pushes every interval until Finish is called.
+func (e *MetricsExporter) Start(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				e.push(now)
+			}
+		}
+	}()
+}
+
+// Finish stops the periodic pushes, sends the final metrics and reports
+// failed pushes to w.
+func (e *MetricsExporter) Finish(w io.Writer) {
+	if e.cancel != nil {
+		e.cancel()
+		<-e.done
+		e.cancel = nil
+	}
+	e.push(time.Now())
+	if e.failures > 0 {
+		fmt.Fprintf(w, "Metrics export: %d of %d pushes failed, e.g. %v\n", e.failures, e.pushes, e.firstErr)
+	}
+}
+
+func (e *MetricsExporter) push(now time.Time) {
+	snapshot := e.snapshot(now)
+	if e.pushgateway != "" {
+		var body bytes.Buffer
+		writePrometheusMetrics(&body, snapshot)
+		e.send(http.MethodPut, e.pushgateway, "text/plain; version=0.0.4", body.Bytes())
+	}
+	if e.otlp != "" {
+		body, err := json.Marshal(otlpMetrics(snapshot))
+		if err != nil {
+			e.failed(err)
+			return
+		}
+		e.send(http.MethodPost, e.otlp, "application/json", body)
+	}
+}
+
+// snapshot takes the current metrics and starts a new interval.
+func (e *MetricsExporter) snapshot(now time.Time) metricsSnapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := metricsSnapshot{
+		target:       e.target,
+		run:          e.run,
+		start:        e.start,
+		at:           now,
+		requests:     e.requests,
+		errors:       make(map[string]int, len(e.errors)),
+		latencySum:   e.latencySum,
+		latencyCount: e.latencyCount,
+		quantiles:    make(map[float64]time.Duration),
+	}
+	for class, count := range e.errors {
+		s.errors[class] = count
+	}
+	if interval := now.Sub(e.lastPush); interval > 0 {
+		s.rps = float64(e.windowN) / interval.Seconds()
+	}
+	if e.window.Count() > 0 {
+		for _, q := range exportQuantiles {
+			s.quantiles[q] = e.window.Percentile(q * 100)
+		}
+	}
+	e.window.Reset()
+	e.windowN = 0
+	e.lastPush = now
+	return s
+}
+
+func (e *MetricsExporter) send(method, url, contentType string, body []byte) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		e.failed(err)
+		return
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.failed(err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		e.failed(fmt.Errorf("%s %s: %s", method, url, resp.Status))
+		return
+	}
+	e.mu.Lock()
+	e.pushes++
+	e.mu.Unlock()
+}
+
+func (e *MetricsExporter) failed(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pushes++
+	e.failures++
+	if e.firstErr == nil {
+		e.firstErr = err
+	}
+}
+
+// writePrometheusMetrics renders the snapshot in the Prometheus text format
+// accepted by the pushgateway.
+func writePrometheusMetrics(w io.Writer, s metricsSnapshot) {
+	labels := `target="` + prometheusLabelEscaper.Replace(s.target) + `"`
+
+	fmt.Fprintln(w, "# TYPE stress_test_requests_total counter")
+	fmt.Fprintf(w, "stress_test_requests_total{%s} %d\n", labels, s.requests)
+
+	fmt.Fprintln(w, "# TYPE stress_test_errors_total counter")
+	classes := make([]string, 0, len(s.errors))
+	for class := range s.errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		fmt.Fprintf(w, "stress_test_errors_total{%s,class=%q} %d\n", labels, class, s.errors[class])
+	}
+
+	fmt.Fprintln(w, "# TYPE stress_test_requests_per_second gauge")
+	fmt.Fprintf(w, "stress_test_requests_per_second{%s} %s\n", labels, prometheusFloat(s.rps))
+
+	fmt.Fprintln(w, "# TYPE stress_test_latency_seconds summary")
+	for _, q := range exportQuantiles {
+		value := math.NaN()
+		if d, ok := s.quantiles[q]; ok {
+			value = d.Seconds()
+		}
+		fmt.Fprintf(w, "stress_test_latency_seconds{%s,quantile=\"%g\"} %s\n", labels, q, prometheusFloat(value))
+	}
+	fmt.Fprintf(w, "stress_test_latency_seconds_sum{%s} %s\n", labels, prometheusFloat(s.latencySum.Seconds()))
+	fmt.Fprintf(w, "stress_test_latency_seconds_count{%s} %d\n", labels, s.latencyCount)
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func prometheusFloat(v float64) string {
+	if math.IsNaN(v) {
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// OTLP/HTTP JSON encoding of the metrics, limited to the fields used here.
+// 64-bit integers are strings, as in the protobuf JSON mapping.
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpMetric struct {
+	Name    string       `json:"name"`
+	Unit    string       `json:"unit,omitempty"`
+	Sum     *otlpSum     `json:"sum,omitempty"`
+	Gauge   *otlpGauge   `json:"gauge,omitempty"`
+	Summary *otlpSummary `json:"summary,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberPoint `json:"dataPoints"`
+}
+
+type otlpSummary struct {
+	DataPoints []otlpSummaryPoint `json:"dataPoints"`
+}
+
+type otlpNumberPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             string          `json:"asInt,omitempty"`
+	AsDouble          *float64        `json:"asDouble,omitempty"`
+}
+
+type otlpSummaryPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	QuantileValues    []otlpQuantile  `json:"quantileValues,omitempty"`
+}
+
+type otlpQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE.
+const otlpCumulative = 2
+
+func otlpAttributes(pairs ...string) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		a := otlpAttribute{Key: pairs[i]}
+		a.Value.StringValue = pairs[i+1]
+		attributes = append(attributes, a)
+	}
+	return attributes
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func otlpMetrics(s metricsSnapshot) otlpRequest {
+	start, at := unixNano(s.start), unixNano(s.at)
+	attributes := otlpAttributes("target", s.target, "run", s.run)
+	counter := func(name string, points []otlpNumberPoint) otlpMetric {
+		return otlpMetric{Name: name, Unit: "1", Sum: &otlpSum{DataPoints: points, AggregationTemporality: otlpCumulative, IsMonotonic: true}}
+	}
+
+	requests := counter("stress_test.requests", []otlpNumberPoint{
+		{Attributes: attributes, StartTimeUnixNano: start, TimeUnixNano: at, AsInt: strconv.Itoa(s.requests)},
+	})
+	classes := make([]string, 0, len(s.errors))
+	for class := range s.errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	var errorPoints []otlpNumberPoint
+	for _, class := range classes {
+		errorPoints = append(errorPoints, otlpNumberPoint{
+			Attributes:        otlpAttributes("target", s.target, "run", s.run, "class", class),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      at,
+			AsInt:             strconv.Itoa(s.errors[class]),
+		})
+	}
+	metrics := []otlpMetric{requests}
+	if len(errorPoints) > 0 {
+		metrics = append(metrics, counter("stress_test.errors", errorPoints))
+	}
+
+	rps := s.rps
+	metrics = append(metrics, otlpMetric{Name: "stress_test.requests_per_second", Unit: "1/s", Gauge: &otlpGauge{
+		DataPoints: []otlpNumberPoint{{Attributes: attributes, TimeUnixNano: at, AsDouble: &rps}},
+	}})
+
+	latency := otlpSummaryPoint{
+		Attributes:        attributes,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      at,
+		Count:             strconv.FormatInt(s.latencyCount, 10),
+		Sum:               s.latencySum.Seconds(),
+	}
+	for _, q := range exportQuantiles {
+		if d, ok := s.quantiles[q]; ok {
+			latency.QuantileValues = append(latency.QuantileValues, otlpQuantile{Quantile: q, Value: d.Seconds()})
+		}
+	}
+	metrics = append(metrics, otlpMetric{Name: "stress_test.latency", Unit: "s", Summary: &otlpSummary{
+		DataPoints: []otlpSummaryPoint{latency},
+	}})
+
+	var rm otlpResourceMetrics
+	rm.Resource.Attributes = otlpAttributes("service.name", "stress-test")
+	var sm otlpScopeMetrics
+	sm.Scope.Name = "stress-test"
+	sm.Metrics = metrics
+	rm.ScopeMetrics = []otlpScopeMetrics{sm}
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{rm}}
+}
@@ -0,0 +1,83 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is a stand-in pushgateway and OTLP receiver that keeps the last
+// body pushed to each path.
+type collector struct {
+	mu     sync.Mutex
+	bodies map[string]string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bodies[r.Method+" "+r.URL.Path] = string(body)
+}
+
+func TestMetricsExporterPushesBothFormats(t *testing.T) {
+	c := &collector{bodies: make(map[string]string)}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	e := NewMetricsExporter(server.URL, server.URL, "http://svc/", start)
+	e.Add(Result{StatusCode: 200, Duration: 100 * time.Millisecond})
+	e.Add(Result{StatusCode: 503, Duration: 300 * time.Millisecond})
+	e.Add(Result{Error: errors.New("boom")})
+	e.Finish(io.Discard)
+
+	prom := c.bodies["PUT /metrics/job/stress_test/run/20240501T120000Z"]
+	for _, want := range []string{
+		`stress_test_requests_total{target="http://svc/"} 3`,
+		`stress_test_errors_total{target="http://svc/",class="5xx"} 1`,
+		`stress_test_errors_total{target="http://svc/",class="other"} 1`,
+		`stress_test_latency_seconds_count{target="http://svc/"} 2`,
+		`stress_test_latency_seconds_sum{target="http://svc/"} 0.4`,
+	} {
+		if !strings.Contains(prom, want) {
+			t.Errorf("pushgateway body lacks %q:\n%s", want, prom)
+		}
+	}
+
+	var otlp otlpRequest
+	if err := json.Unmarshal([]byte(c.bodies["POST /v1/metrics"]), &otlp); err != nil {
+		t.Fatalf("invalid OTLP body: %v", err)
+	}
+	metrics := map[string]otlpMetric{}
+	for _, m := range otlp.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	if got := metrics["stress_test.requests"].Sum.DataPoints[0].AsInt; got != "3" {
+		t.Errorf("expected 3 requests, got %s", got)
+	}
+	if got := len(metrics["stress_test.errors"].Sum.DataPoints); got != 2 {
+		t.Errorf("expected 2 error classes, got %d", got)
+	}
+	if got := metrics["stress_test.latency"].Summary.DataPoints[0]; got.Count != "2" || len(got.QuantileValues) != len(exportQuantiles) {
+		t.Errorf("unexpected latency summary %+v", got)
+	}
+}
+
+func TestExportURLs(t *testing.T) {
+	if got := otlpURL("http://collector:4318"); got != "http://collector:4318/v1/metrics" {
+		t.Errorf("unexpected OTLP URL %s", got)
+	}
+	if got := otlpURL("http://collector:4318/custom"); got != "http://collector:4318/custom" {
+		t.Errorf("unexpected OTLP URL %s", got)
+	}
+	if got := pushgatewayURL("http://gw:9091/metrics/job/load", time.Now()); got != "http://gw:9091/metrics/job/load" {
+		t.Errorf("unexpected pushgateway URL %s", got)
+	}
+}
@@ -0,0 +1,270 @@
+package loadtest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCSpec describes a unary gRPC call. The request body is JSON and, like
+// HTTP bodies, may contain templates, including rows of DataFile.
+type GRPCSpec struct {
+	Target      string
+	Method      string
+	Body        string
+	DataFile    string
+	Headers     []string
+	ProtoFiles  []string
+	ImportPaths []string
+	TLS         bool
+	Insecure    bool
+	Timeout     time.Duration
+	Assertions  Assertions
+}
+
+// GRPCTarget sends one unary call per job over a shared connection. Its
+// results carry the gRPC status code instead of an HTTP one.
+type GRPCTarget struct {
+	conn       *grpc.ClientConn
+	method     protoreflect.MethodDescriptor
+	path       string
+	body       *RequestTemplate
+	metadata   metadata.MD
+	timeout    time.Duration
+	assertions Assertions
+}
+
+func NewGRPCTarget(ctx context.Context, spec GRPCSpec) (*GRPCTarget, error) {
+	serviceName, methodName, err := splitGRPCMethod(spec.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if spec.TLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: spec.Insecure})
+	}
+	conn, err := grpc.NewClient(spec.Target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", spec.Target, err)
+	}
+
+	var service protoreflect.ServiceDescriptor
+	if len(spec.ProtoFiles) > 0 {
+		service, err = serviceFromProtoFiles(ctx, spec.ProtoFiles, spec.ImportPaths, serviceName)
+	} else {
+		service, err = serviceFromReflection(ctx, conn, serviceName)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		conn.Close()
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		conn.Close()
+		return nil, fmt.Errorf("method %s is streaming, only unary methods are supported", spec.Method)
+	}
+
+	body := spec.Body
+	if body == "" {
+		body = "{}"
+	}
+	tmpl, err := NewRequestTemplate(RequestSpec{Body: body, DataFile: spec.DataFile})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	md := metadata.MD{}
+	for _, header := range spec.Headers {
+		key, value, _ := strings.Cut(header, ":")
+		md.Append(strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value))
+	}
+
+	return &GRPCTarget{
+		conn:       conn,
+		method:     method,
+		path:       fmt.Sprintf("/%s/%s", serviceName, methodName),
+		body:       tmpl,
+		metadata:   md,
+		timeout:    spec.Timeout,
+		assertions: spec.Assertions,
+	}, nil
+}
+
+// splitGRPCMethod accepts pkg.Service/Method or pkg.Service.Method.
+func splitGRPCMethod(fullMethod string) (string, string, error) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	i := strings.LastIndexAny(fullMethod, "/.")
+	if i <= 0 || i == len(fullMethod)-1 {
+		return "", "", fmt.Errorf("invalid gRPC method %q, expected package.Service/Method", fullMethod)
+	}
+	return fullMethod[:i], fullMethod[i+1:], nil
+}
+
+func (t *GRPCTarget) Close() error {
+	return t.conn.Close()
+}
+
+// Run sends the unary call of a job.
+func (t *GRPCTarget) Run(_ *http.Client, j Job, results chan<- Result) {
+	startTime := jobStart(j)
+	result := Result{Timestamp: startTime, Proto: "grpc"}
+
+	data := templateData{Seq: j.Seq}
+	if rows := t.body.rows; len(rows) > 0 {
+		data.Row = rows[j.Seq%len(rows)]
+	}
+	request := dynamicpb.NewMessage(t.method.Input())
+	body, err := render(t.body.body, data)
+	if err == nil {
+		err = protojson.Unmarshal([]byte(body), request)
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("building request: %w", err)
+		result.Duration = time.Since(startTime)
+		results <- result
+		return
+	}
+
+	ctx := metadata.NewOutgoingContext(context.Background(), t.metadata)
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	response := dynamicpb.NewMessage(t.method.Output())
+	var header metadata.MD
+	err = t.conn.Invoke(ctx, t.path, request, response, grpc.Header(&header))
+	result.Duration = time.Since(startTime)
+	result.GRPCCode = status.Code(err)
+	if len(t.assertions) > 0 {
+		var body []byte
+		if err == nil && t.assertions.needsBody() {
+			body, _ = protojson.Marshal(response)
+		}
+		t.assertions.apply(&result, grpcResponse(result, header, body))
+	}
+	results <- result
+}
+
+func serviceFromProtoFiles(ctx context.Context, files, importPaths []string, serviceName string) (protoreflect.ServiceDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	compiled, err := compiler.Compile(ctx, files...)
+	if err != nil {
+		return nil, fmt.Errorf("compiling proto files: %w", err)
+	}
+	for _, file := range compiled {
+		if d, ok := file.FindDescriptorByName(protoreflect.FullName(serviceName)).(protoreflect.ServiceDescriptor); ok {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("service %s not found in %s", serviceName, strings.Join(files, ", "))
+}
+
+// serviceFromReflection downloads the file descriptors of a service, and of
+// everything it imports, through the server reflection API.
+func serviceFromReflection(ctx context.Context, conn *grpc.ClientConn, serviceName string) (protoreflect.ServiceDescriptor, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+	defer stream.CloseSend()
+
+	protos := make(map[string]*descriptorpb.FileDescriptorProto)
+	request := &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	}
+	for request != nil {
+		if err := stream.Send(request); err != nil {
+			return nil, fmt.Errorf("server reflection: %w", err)
+		}
+		response, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("server reflection: %w", err)
+		}
+		if e := response.GetErrorResponse(); e != nil {
+			return nil, fmt.Errorf("server reflection: %s", e.GetErrorMessage())
+		}
+		for _, raw := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return nil, fmt.Errorf("server reflection: %w", err)
+			}
+			protos[fd.GetName()] = fd
+		}
+
+		// Ask for imports the server did not send along, unless they are
+		// well-known files already linked into this binary
+		request = nil
+		for _, fd := range protos {
+			for _, dep := range fd.GetDependency() {
+				if _, ok := protos[dep]; ok {
+					continue
+				}
+				if known, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+					protos[dep] = protodesc.ToFileDescriptorProto(known)
+					continue
+				}
+				request = &reflectionpb.ServerReflectionRequest{
+					MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+				}
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range protos {
+		set.File = append(set.File, fd)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", serviceName, err)
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	return service, nil
+}
+
+// grpcResponse adapts a call outcome to assertions: the status is the code
+// name and headers are the response metadata.
+func grpcResponse(result Result, header metadata.MD, body []byte) response {
+	return response{
+		status:   result.GRPCCode.String(),
+		header:   func(name string) bool { return len(header.Get(name)) > 0 },
+		body:     body,
+		duration: result.Duration,
+	}
+}
@@ -0,0 +1,103 @@
+package loadtest
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+const healthProto = `syntax = "proto3";
+
+package grpc.health.v1;
+
+message HealthCheckRequest {
+  string service = 1;
+}
+
+message HealthCheckResponse {
+  enum ServingStatus {
+    UNKNOWN = 0;
+    SERVING = 1;
+    NOT_SERVING = 2;
+    SERVICE_UNKNOWN = 3;
+  }
+  ServingStatus status = 1;
+}
+
+service Health {
+  rpc Check(HealthCheckRequest) returns (HealthCheckResponse);
+}
+`
+
+// startHealthServer serves the standard health service, which knows the ""
+// and "orders" services, with server reflection.
+func startHealthServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestGRPCTarget(t *testing.T) {
+	addr := startHealthServer(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "health.proto"), []byte(healthProto), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dataFile := filepath.Join(dir, "services.csv")
+	if err := os.WriteFile(dataFile, []byte("service\norders\nmissing\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	specs := map[string]GRPCSpec{
+		"reflection":  {},
+		"proto files": {ProtoFiles: []string{"health.proto"}, ImportPaths: []string{dir}},
+	}
+	for name, spec := range specs {
+		t.Run(name, func(t *testing.T) {
+			spec.Target = addr
+			spec.Method = "grpc.health.v1.Health/Check"
+			spec.Body = `{"service": "{{.Row.service}}"}`
+			spec.DataFile = dataFile
+			target, err := NewGRPCTarget(context.Background(), spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer target.Close()
+
+			runner := Runner{Target: target, Config: LoadConfig{Requests: 4, Concurrency: 1}}
+			report, err := runner.Run(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The rows alternate between a known and an unknown service
+			codes := report.Stats.GRPCCodes
+			if codes["OK"] != 2 || codes["NotFound"] != 2 {
+				t.Errorf("expected 2 OK and 2 NotFound calls, got %v", codes)
+			}
+		})
+	}
+
+	if _, err := NewGRPCTarget(context.Background(), GRPCSpec{Target: addr, Method: "grpc.health.v1.Health/Missing"}); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+	if _, err := NewGRPCTarget(context.Background(), GRPCSpec{Target: addr, Method: "grpc.health.v1.Health/Watch"}); err == nil {
+		t.Error("expected an error for a streaming method")
+	}
+}
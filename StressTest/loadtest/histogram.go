@@ -0,0 +1,197 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// The histogram uses HDR-style log-linear buckets: values below
+// subBucketCount are stored exactly and every power of two above that is
+// split into subBucketHalf linear sub-buckets, which keeps the relative
+// error under 1/subBucketHalf (~1.6%) with a fixed memory footprint.
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+	bucketCount    = subBucketCount + (64-subBucketBits)*subBucketHalf
+)
+
+// Histogram records latencies without keeping the individual samples.
+type Histogram struct {
+	counts []int64
+	total  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{
+		counts: make([]int64, bucketCount),
+		min:    math.MaxInt64,
+	}
+}
+
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return subBucketCount + (shift-1)*subBucketHalf + int(v>>shift) - subBucketHalf
+}
+
+// bucketRange returns the lowest and highest value stored in a bucket.
+func bucketRange(idx int) (int64, int64) {
+	if idx < subBucketCount {
+		return int64(idx), int64(idx)
+	}
+	shift := (idx-subBucketCount)/subBucketHalf + 1
+	sub := int64((idx-subBucketCount)%subBucketHalf + subBucketHalf)
+	return sub << shift, (sub+1)<<shift - 1
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+	h.counts[bucketIndex(v)]++
+	h.total++
+	h.sum += v
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge adds the samples of other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.total == 0 {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// histogramJSON is the wire form of a histogram: only non-empty buckets are
+// sent, as [index, count] pairs.
+type histogramJSON struct {
+	Buckets [][2]int64 `json:"buckets"`
+	Sum     int64      `json:"sum"`
+	Min     int64      `json:"min"`
+	Max     int64      `json:"max"`
+}
+
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	wire := histogramJSON{Sum: h.sum, Min: h.min, Max: h.max, Buckets: [][2]int64{}}
+	for idx, c := range h.counts {
+		if c > 0 {
+			wire.Buckets = append(wire.Buckets, [2]int64{int64(idx), c})
+		}
+	}
+	return json.Marshal(wire)
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var wire histogramJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*h = *NewHistogram()
+	for _, bucket := range wire.Buckets {
+		idx, c := bucket[0], bucket[1]
+		if idx < 0 || idx >= bucketCount {
+			return fmt.Errorf("histogram bucket %d out of range", idx)
+		}
+		h.counts[idx] += c
+		h.total += c
+	}
+	h.sum, h.min, h.max = wire.Sum, wire.Min, wire.Max
+	return nil
+}
+
+// Reset empties the histogram so it can be reused.
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.total, h.sum, h.min, h.max = 0, 0, math.MaxInt64, 0
+}
+
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+func (h *Histogram) Min() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min)
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max)
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / h.total)
+}
+
+// Percentile returns the latency below which p percent of the samples fall.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(p / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for idx, c := range h.counts {
+		seen += c
+		if seen >= target {
+			_, high := bucketRange(idx)
+			return time.Duration(clamp(high, h.min, h.max))
+		}
+	}
+	return time.Duration(h.max)
+}
+
+// ForEach calls fn for every non-empty bucket with the value that
+// represents it, in increasing order.
+func (h *Histogram) ForEach(fn func(value time.Duration, count int64)) {
+	for idx, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		low, high := bucketRange(idx)
+		fn(time.Duration(clamp(low+(high-low)/2, h.min, h.max)), c)
+	}
+}
+
+func clamp(v, low, high int64) int64 {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
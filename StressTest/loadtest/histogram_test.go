@@ -0,0 +1,42 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	if h.Count() != 1000 {
+		t.Fatalf("expected 1000 samples, got %d", h.Count())
+	}
+	if h.Min() != time.Millisecond || h.Max() != time.Second {
+		t.Errorf("unexpected min/max: %v/%v", h.Min(), h.Max())
+	}
+
+	cases := map[float64]time.Duration{
+		50:   500 * time.Millisecond,
+		90:   900 * time.Millisecond,
+		99:   990 * time.Millisecond,
+		99.9: 999 * time.Millisecond,
+	}
+	for p, want := range cases {
+		got := h.Percentile(p)
+		if diff := float64(got-want) / float64(want); diff < -0.02 || diff > 0.02 {
+			t.Errorf("p%v: expected ~%v, got %v", p, want, got)
+		}
+	}
+}
+
+func TestBucketRangeContainsValue(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, 1000, 123456789, 1 << 62} {
+		low, high := bucketRange(bucketIndex(v))
+		if v < low || v > high {
+			t.Errorf("value %d outside bucket [%d, %d]", v, low, high)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package loadtest
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The HTML report is a single file: styles and charts (SVG) are inline so it
+// can be attached to a ticket and opened offline.
+
+const (
+	chartWidth  = 860
+	chartHeight = 260
+	chartLeft   = 64
+	chartRight  = 16
+	chartTop    = 16
+	chartBottom = 36
+)
+
+var chartColors = []string{"#4e79a7", "#f28e2b", "#e15759", "#59a14f", "#76b7b2", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+type chartSeries struct {
+	Name   string
+	Values []float64
+}
+
+type htmlRow struct {
+	Name  string
+	Value string
+}
+
+type htmlReport struct {
+	Title       string
+	StartedAt   string
+	Summary     []htmlRow
+	Latency     []htmlRow
+	Statuses    []htmlRow
+	Thresholds  []ThresholdResult
+	Charts      []htmlChart
+	Steps       []htmlStep
+	Breakdown   string
+	Interrupted bool
+}
+
+type htmlChart struct {
+	Title string
+	SVG   template.HTML
+}
+
+type htmlStep struct {
+	Name    string
+	Summary []htmlRow
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Stress test report - {{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 920px; color: #222; }
+h1 { font-size: 1.5em; margin-bottom: 0; }
+h2 { font-size: 1.15em; margin-top: 2em; border-bottom: 1px solid #ddd; padding-bottom: .3em; }
+.sub { color: #666; margin-top: .3em; }
+.tables { display: flex; flex-wrap: wrap; gap: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: .25em .8em; text-align: left; border-bottom: 1px solid #eee; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.pass { color: #2e7d32; font-weight: bold; }
+.fail { color: #c62828; font-weight: bold; }
+svg { font-size: 11px; }
+svg .axis { stroke: #999; }
+svg .grid { stroke: #eee; }
+.legend span { display: inline-block; margin-right: 1.2em; }
+.legend i { display: inline-block; width: .8em; height: .8em; margin-right: .3em; }
+</style>
+</head>
+<body>
+<h1>Stress test report</h1>
+<p class="sub">{{.Title}} &mdash; started {{.StartedAt}}{{if .Interrupted}} &mdash; interrupted{{end}}</p>
+
+<div class="tables">
+<table>
+{{range .Summary}}<tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+<table>
+{{range .Latency}}<tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+<table>
+{{range .Statuses}}<tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+</div>
+
+{{if .Thresholds}}<h2>Thresholds</h2>
+<table>
+{{range .Thresholds}}<tr><td class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}PASS{{else}}FAIL{{end}}</td><td>{{.Expression}}</td><td>actual {{.Actual}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{range .Charts}}<h2>{{.Title}}</h2>
+{{.SVG}}
+{{end}}
+
+{{range .Steps}}<h2>{{$.Breakdown}}: {{.Name}}</h2>
+<table>
+{{range .Summary}}<tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTMLReport renders the report of a finished run.
+func writeHTMLReport(w io.Writer, title, breakdown string, startedAt time.Time, totalDuration time.Duration, stats *Stats, series *TimeSeries, thresholds []ThresholdResult, interrupted bool) error {
+	report := htmlReport{
+		Title:       title,
+		StartedAt:   startedAt.Format(time.RFC1123),
+		Summary:     statsRows(stats),
+		Thresholds:  thresholds,
+		Breakdown:   breakdown,
+		Interrupted: interrupted,
+	}
+	report.Summary = append([]htmlRow{
+		{"Total time", round(totalDuration).String()},
+		{"Requests per second", strconv.FormatFloat(stats.RequestsPerSecond(totalDuration), 'f', 2, 64)},
+	}, report.Summary...)
+	report.Latency = latencyRows(stats.Latency)
+	report.Statuses = statusRows(stats)
+
+	points := series.Points()
+	seconds := make([]float64, len(points))
+	p50 := make([]float64, len(points))
+	p95 := make([]float64, len(points))
+	p99 := make([]float64, len(points))
+	rps := make([]float64, len(points))
+	errs := make([]float64, len(points))
+	for i, point := range points {
+		seconds[i] = float64(point.Second)
+		p50[i], p95[i], p99[i] = milliseconds(point.P50), milliseconds(point.P95), milliseconds(point.P99)
+		rps[i], errs[i] = float64(point.Requests), float64(point.Errors)
+	}
+	labels := series.Statuses()
+	statuses := make([]chartSeries, len(labels))
+	for i, label := range labels {
+		statuses[i] = chartSeries{Name: label, Values: make([]float64, len(points))}
+		for j, point := range points {
+			statuses[i].Values[j] = float64(point.Statuses[label])
+		}
+	}
+
+	report.Charts = []htmlChart{
+		{"Latency over time", lineChart(seconds, []chartSeries{{"p50", p50}, {"p95", p95}, {"p99", p99}}, formatMs)},
+		{"Throughput over time (requests per second)", lineChart(seconds, []chartSeries{{"requests", rps}, {"errors", errs}}, formatFloat)},
+		{"Status codes over time (responses per second)", lineChart(seconds, statuses, formatFloat)},
+		{"Latency distribution", distributionChart(stats.Latency)},
+	}
+
+	for _, name := range stats.StepNames {
+		step := stats.Steps[name]
+		rows := append(statsRows(step), latencyRows(step.Latency)...)
+		report.Steps = append(report.Steps, htmlStep{Name: name, Summary: append(rows, statusRows(step)...)})
+	}
+	return htmlTemplate.Execute(w, report)
+}
+
+func statsRows(stats *Stats) []htmlRow {
+	return []htmlRow{
+		{"Total requests", strconv.Itoa(stats.TotalRequests)},
+		{"Successful", strconv.Itoa(stats.SuccessCount)},
+		{"Failed (no response)", strconv.Itoa(stats.FailedCount)},
+		{"Failed assertions", strconv.Itoa(stats.AssertionFailed)},
+		{"Error rate", strconv.FormatFloat(stats.ratio("error_rate")*100, 'f', 2, 64) + "%"},
+	}
+}
+
+func latencyRows(h *Histogram) []htmlRow {
+	if h.Count() == 0 {
+		return nil
+	}
+	rows := []htmlRow{
+		{"min", round(h.Min()).String()},
+		{"mean", round(h.Mean()).String()},
+	}
+	for _, p := range reportPercentiles {
+		rows = append(rows, htmlRow{percentileName(p), round(h.Percentile(p)).String()})
+	}
+	return append(rows, htmlRow{"max", round(h.Max()).String()})
+}
+
+func statusRows(stats *Stats) []htmlRow {
+	var rows []htmlRow
+	codes := make([]int, 0, len(stats.StatusCodes))
+	for code := range stats.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		rows = append(rows, htmlRow{"HTTP " + strconv.Itoa(code), strconv.Itoa(stats.StatusCodes[code])})
+	}
+	names := make([]string, 0, len(stats.GRPCCodes)+len(stats.ErrorClasses))
+	for name := range stats.GRPCCodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rows = append(rows, htmlRow{"gRPC " + name, strconv.Itoa(stats.GRPCCodes[name])})
+	}
+	names = names[:0]
+	for class := range stats.ErrorClasses {
+		names = append(names, class)
+	}
+	sort.Strings(names)
+	for _, class := range names {
+		rows = append(rows, htmlRow{"Error " + class, strconv.Itoa(stats.ErrorClasses[class].Count)})
+	}
+	names = names[:0]
+	for expression := range stats.AssertionFailures {
+		names = append(names, expression)
+	}
+	sort.Strings(names)
+	for _, expression := range names {
+		rows = append(rows, htmlRow{"Failed " + expression, strconv.Itoa(stats.AssertionFailures[expression])})
+	}
+	return rows
+}
+
+// lineChart draws one polyline per series over the seconds of the run.
+func lineChart(xs []float64, series []chartSeries, formatY func(float64) string) template.HTML {
+	var peak, lastX float64
+	for _, s := range series {
+		for _, v := range s.Values {
+			peak = math.Max(peak, v)
+		}
+	}
+	if len(xs) > 0 {
+		lastX = xs[len(xs)-1]
+	}
+	yMax := niceCeil(peak)
+	xMax := math.Max(lastX, 1)
+
+	var b strings.Builder
+	openChart(&b)
+	plotW, plotH := float64(chartWidth-chartLeft-chartRight), float64(chartHeight-chartTop-chartBottom)
+	x := func(v float64) float64 { return chartLeft + v/xMax*plotW }
+	y := func(v float64) float64 { return chartTop + plotH - v/yMax*plotH }
+	yAxis(&b, yMax, formatY)
+	for _, tick := range niceTicks(xMax) {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%ss</text>`, x(tick), chartHeight-chartBottom+16, formatFloat(tick))
+	}
+
+	for i, s := range series {
+		if len(s.Values) == 0 {
+			continue
+		}
+		points := make([]string, len(s.Values))
+		for j, v := range s.Values {
+			points[j] = fmt.Sprintf("%.1f,%.1f", x(xs[j]), y(v))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"><title>%s</title></polyline>`,
+			chartColors[i%len(chartColors)], strings.Join(points, " "), html.EscapeString(s.Name))
+	}
+	b.WriteString(`</svg>`)
+	legend(&b, series)
+	return template.HTML(b.String())
+}
+
+// distributionChart draws the latency histogram as bars.
+func distributionChart(h *Histogram) template.HTML {
+	var b strings.Builder
+	openChart(&b)
+	if h.Count() == 0 {
+		b.WriteString(`</svg>`)
+		return template.HTML(b.String())
+	}
+	low, step, counts := histogramBins(h, 40)
+	var peak int64
+	for _, c := range counts {
+		if c > peak {
+			peak = c
+		}
+	}
+	yMax := niceCeil(float64(peak))
+	plotW, plotH := float64(chartWidth-chartLeft-chartRight), float64(chartHeight-chartTop-chartBottom)
+	barW := plotW / float64(len(counts))
+	yAxis(&b, yMax, formatFloat)
+
+	for i, c := range counts {
+		barH := float64(c) / yMax * plotH
+		from, to := low+step*time.Duration(i), low+step*time.Duration(i+1)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%v - %v: %d</title></rect>`,
+			chartLeft+float64(i)*barW+0.5, chartTop+plotH-barH, math.Max(barW-1, 1), barH, chartColors[0], round(from), round(to), c)
+		if i%(len(counts)/8+1) == 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%v</text>`, chartLeft+float64(i)*barW, chartHeight-chartBottom+16, round(from))
+		}
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func openChart(b *strings.Builder) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartLeft, chartHeight-chartBottom, chartWidth-chartRight, chartHeight-chartBottom)
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartLeft, chartTop, chartLeft, chartHeight-chartBottom)
+}
+
+// yAxis draws horizontal grid lines with their labels.
+func yAxis(b *strings.Builder, yMax float64, format func(float64) string) {
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	for _, tick := range niceTicks(yMax) {
+		y := chartTop + plotH - tick/yMax*plotH
+		fmt.Fprintf(b, `<line class="grid" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, chartLeft+1, y, chartWidth-chartRight, y)
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`, chartLeft-6, y, html.EscapeString(format(tick)))
+	}
+}
+
+func legend(b *strings.Builder, series []chartSeries) {
+	b.WriteString(`<div class="legend">`)
+	for i, s := range series {
+		fmt.Fprintf(b, `<span><i style="background:%s"></i>%s</span>`, chartColors[i%len(chartColors)], html.EscapeString(s.Name))
+	}
+	b.WriteString(`</div>`)
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// niceTicks returns about five round values between zero and max.
+func niceTicks(max float64) []float64 {
+	step := niceCeil(max / 5)
+	var ticks []float64
+	for v := 0.0; v <= max*1.0001; v += step {
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+// formatFloat prints axis values without floating point noise.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
@@ -0,0 +1,47 @@
+package loadtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLReportRendersSummaryAndCharts(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	stats := NewStats()
+	series := NewTimeSeries(start)
+	for i := 0; i < 4; i++ {
+		result := Result{Timestamp: start.Add(time.Duration(i) * 500 * time.Millisecond), StatusCode: 200, Duration: 100 * time.Millisecond}
+		if i == 3 {
+			result.StatusCode = 503
+		}
+		stats.Add(result)
+		series.Add(result)
+	}
+	thresholds := []ThresholdResult{{Expression: "error_rate<1%", Actual: "25.00%", Passed: false}}
+
+	var buf bytes.Buffer
+	if err := writeHTMLReport(&buf, "http://localhost:8080", "", start, 2*time.Second, stats, series, thresholds, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{
+		"<p class=\"sub\">http://localhost:8080 &mdash; started Mon, 19 Oct 2026 12:00:00 UTC</p>",
+		"<tr><th>Total requests</th><td class=\"num\">4</td></tr>",
+		"<tr><th>Requests per second</th><td class=\"num\">2.00</td></tr>",
+		"<tr><th>Error rate</th><td class=\"num\">25.00%</td></tr>",
+		"<tr><th>p50</th><td class=\"num\">100ms</td></tr>",
+		"<tr><th>HTTP 503</th><td class=\"num\">1</td></tr>",
+		"<td class=\"fail\">FAIL</td><td>error_rate&lt;1%</td><td>actual 25.00%</td>",
+		"<h2>Latency over time</h2>\n<svg",
+		"<h2>Latency distribution</h2>\n<svg",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected the report to contain %q", want)
+		}
+	}
+	if n := strings.Count(html, "<svg"); n != 4 {
+		t.Errorf("expected 4 charts, got %d", n)
+	}
+}
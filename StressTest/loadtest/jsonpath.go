@@ -0,0 +1,103 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathLookup evaluates a simple JSONPath expression such as
+// $.data.items[0].id or $['id'] against a JSON document. Only child and
+// index selectors are supported, which covers extracting ids from responses.
+func jsonPathLookup(document []byte, path string) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	selectors, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, selector := range selectors {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[selector]
+			if !ok {
+				return nil, fmt.Errorf("%s: key %q not found", path, selector)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(selector)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("%s: index %q out of range", path, selector)
+			}
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("%s: cannot select %q from a scalar", path, selector)
+		}
+	}
+	return value, nil
+}
+
+// jsonPathString returns the value at path formatted for use in templates.
+func jsonPathString(document []byte, path string) (string, error) {
+	value, err := jsonPathLookup(document, path)
+	if err != nil {
+		return "", err
+	}
+	return jsonValueString(value), nil
+}
+
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func parseJSONPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q, must start with $", path)
+	}
+	var selectors []string
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q", path)
+			}
+			selectors = append(selectors, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q, missing ]", path)
+			}
+			selectors = append(selectors, strings.Trim(rest[1:end], `'"`))
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q", path)
+		}
+	}
+	return selectors, nil
+}
@@ -0,0 +1,32 @@
+package loadtest
+
+import "testing"
+
+func TestJSONPathString(t *testing.T) {
+	document := []byte(`{"id":"a1","amount":12.5,"bids":[{"user":"u1"},{"user":"u2"}],"meta":{"count":2}}`)
+
+	cases := map[string]string{
+		"$.id":           "a1",
+		"$['id']":        "a1",
+		"$.amount":       "12.5",
+		"$.bids[1].user": "u2",
+		"$.meta.count":   "2",
+		"$.meta":         `{"count":2}`,
+	}
+	for path, want := range cases {
+		got, err := jsonPathString(document, path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+
+	for _, path := range []string{"$.missing", "$.bids[5].user", "$.id.value", "id"} {
+		if _, err := jsonPathString(document, path); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadConfig describes how many requests are sent and when.
+//
+// Without a rate the workers run closed-loop: each one sends the next request
+// as soon as the previous response arrives. With a rate (or stages) requests
+// follow a fixed schedule independent of the responses, and latency is measured
+// from the intended send time so a slow server cannot hide queueing delay
+// (coordinated omission). A Schedule gives the send offset of each request
+// explicitly, as when replaying recorded traffic.
+type LoadConfig struct {
+	Requests    int
+	Concurrency int
+	Duration    time.Duration
+	Rate        float64
+	Stages      []Stage
+	Schedule    []time.Duration
+}
+
+// Stage ramps the request rate linearly to Target over Duration.
+type Stage struct {
+	Duration time.Duration
+	Target   float64
+}
+
+type Job struct {
+	Seq      int
+	Intended time.Time
+}
+
+func (c LoadConfig) OpenModel() bool {
+	return c.Rate > 0 || len(c.Stages) > 0 || len(c.Schedule) > 0
+}
+
+func (c LoadConfig) Validate() error {
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be greater than zero")
+	}
+	if c.Requests < 0 || c.Duration < 0 || c.Rate < 0 {
+		return fmt.Errorf("requests, duration and rate must not be negative")
+	}
+	if c.Requests == 0 && c.Duration == 0 && len(c.Stages) == 0 && len(c.Schedule) == 0 {
+		return fmt.Errorf("either requests, duration or stages must be set")
+	}
+	return nil
+}
+
+// stagesDuration is the total length of the ramp stages.
+func (c LoadConfig) stagesDuration() time.Duration {
+	var total time.Duration
+	for _, s := range c.Stages {
+		total += s.Duration
+	}
+	return total
+}
+
+// RunDuration is how long the test may run, zero meaning no time limit.
+func (c LoadConfig) RunDuration() time.Duration {
+	if c.Duration > 0 {
+		return c.Duration
+	}
+	return c.stagesDuration()
+}
+
+// rateAt returns the scheduled requests per second at the given offset.
+func (c LoadConfig) rateAt(elapsed time.Duration) float64 {
+	from := c.Rate
+	for _, s := range c.Stages {
+		if elapsed < s.Duration {
+			return from + (s.Target-from)*float64(elapsed)/float64(s.Duration)
+		}
+		elapsed -= s.Duration
+		from = s.Target
+	}
+	return from
+}
+
+// feedJobs sends jobs until the request count or the run duration is
+// reached, or ctx is canceled, then closes the channel.
+func feedJobs(ctx context.Context, cfg LoadConfig, jobs chan<- Job) {
+	defer close(jobs)
+	start := time.Now()
+	var deadline time.Time
+	if d := cfg.RunDuration(); d > 0 {
+		deadline = start.Add(d)
+	}
+
+	if !cfg.OpenModel() {
+		for i := 0; cfg.Requests == 0 || i < cfg.Requests; i++ {
+			if !deadline.IsZero() && !time.Now().Before(deadline) {
+				return
+			}
+			select {
+			case jobs <- Job{Seq: i}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		return
+	}
+
+	// Open model: the send time of request i+1 is derived from the schedule
+	// alone, never from when request i was actually dispatched
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	if len(cfg.Schedule) > 0 {
+		for i, offset := range cfg.Schedule {
+			intended := start.Add(offset)
+			if cfg.Requests > 0 && i >= cfg.Requests || !deadline.IsZero() && !intended.Before(deadline) {
+				return
+			}
+			if !sendAt(ctx, timer, jobs, Job{Seq: i, Intended: intended}) {
+				return
+			}
+		}
+		return
+	}
+	var offset time.Duration
+	for i := 0; cfg.Requests == 0 || i < cfg.Requests; i++ {
+		rate := cfg.rateAt(offset)
+		for rate <= 0 {
+			offset += 10 * time.Millisecond
+			if !deadline.IsZero() && !start.Add(offset).Before(deadline) {
+				return
+			}
+			rate = cfg.rateAt(offset)
+		}
+		intended := start.Add(offset)
+		if !deadline.IsZero() && !intended.Before(deadline) {
+			return
+		}
+		if !sendAt(ctx, timer, jobs, Job{Seq: i, Intended: intended}) {
+			return
+		}
+		offset += time.Duration(float64(time.Second) / rate)
+	}
+}
+
+// sendAt waits until the intended time of j and hands it to a worker. It
+// returns false when ctx is canceled first.
+func sendAt(ctx context.Context, timer *time.Timer, jobs chan<- Job, j Job) bool {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(time.Until(j.Intended))
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return false
+	}
+	select {
+	case jobs <- j:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ParseStages reads a comma separated list of "duration:rps" ramp stages,
+// for example "60s:500,2m:500".
+func ParseStages(value string) ([]Stage, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var stages []Stage
+	for _, part := range strings.Split(value, ",") {
+		durationText, targetText, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid stage %q, expected duration:rps", part)
+		}
+		duration, err := time.ParseDuration(durationText)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid stage duration %q", durationText)
+		}
+		target, err := strconv.ParseFloat(targetText, 64)
+		if err != nil || target < 0 {
+			return nil, fmt.Errorf("invalid stage rate %q", targetText)
+		}
+		stages = append(stages, Stage{Duration: duration, Target: target})
+	}
+	return stages, nil
+}
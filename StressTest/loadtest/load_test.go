@@ -0,0 +1,62 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateAtRampsBetweenStages(t *testing.T) {
+	stages, err := ParseStages("60s:500,30s:500")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := LoadConfig{Rate: 10, Stages: stages}
+
+	cases := map[time.Duration]float64{
+		0:                10,
+		30 * time.Second: 255,
+		60 * time.Second: 500,
+		80 * time.Second: 500,
+		2 * time.Minute:  500,
+	}
+	for elapsed, want := range cases {
+		if got := cfg.rateAt(elapsed); got != want {
+			t.Errorf("rateAt(%v): expected %v, got %v", elapsed, want, got)
+		}
+	}
+	if cfg.RunDuration() != 90*time.Second {
+		t.Errorf("expected run duration of 90s, got %v", cfg.RunDuration())
+	}
+}
+
+func TestFeedJobsFollowsSchedule(t *testing.T) {
+	cfg := LoadConfig{Concurrency: 1, Rate: 100, Requests: 5}
+	jobs := make(chan Job, cfg.Requests)
+	feedJobs(context.Background(), cfg, jobs)
+
+	var previous time.Time
+	for j := range jobs {
+		if !previous.IsZero() && j.Intended.Sub(previous) != 10*time.Millisecond {
+			t.Errorf("job %d: expected 10ms after previous, got %v", j.Seq, j.Intended.Sub(previous))
+		}
+		previous = j.Intended
+	}
+}
+
+func TestFeedJobsFollowsExplicitSchedule(t *testing.T) {
+	cfg := LoadConfig{Concurrency: 1, Requests: 2, Schedule: []time.Duration{0, 20 * time.Millisecond, 40 * time.Millisecond}}
+	jobs := make(chan Job, 3)
+	feedJobs(context.Background(), cfg, jobs)
+
+	var intended []time.Time
+	for j := range jobs {
+		intended = append(intended, j.Intended)
+	}
+	if len(intended) != 2 {
+		t.Fatalf("expected the request count to cut the schedule to 2 jobs, got %d", len(intended))
+	}
+	if d := intended[1].Sub(intended[0]); d != 20*time.Millisecond {
+		t.Errorf("expected jobs 20ms apart, got %v", d)
+	}
+}
@@ -0,0 +1,310 @@
+package loadtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Summary is the machine-readable form of the report.
+type Summary struct {
+	URL               string    `json:"url,omitempty"`
+	Scenario          string    `json:"scenario,omitempty"`
+	Concurrency       int       `json:"concurrency"`
+	TargetRate        float64   `json:"target_rate,omitempty"`
+	StartedAt         time.Time `json:"started_at"`
+	DurationSeconds   float64   `json:"duration_seconds"`
+	RequestsPerSecond float64   `json:"requests_per_second"`
+	StatsSummary
+	Connections ConnectionSummary `json:"connections"`
+	Steps       []StepSummary     `json:"steps,omitempty"`
+	Thresholds  []ThresholdResult `json:"thresholds,omitempty"`
+}
+
+// StatsSummary holds the counters shared by the run and each scenario step.
+type StatsSummary struct {
+	TotalRequests int                         `json:"total_requests"`
+	SuccessCount  int                         `json:"success_count"`
+	FailedCount   int                         `json:"failed_count"`
+	StatusCodes   map[int]int                 `json:"status_codes"`
+	GRPCCodes     map[string]int              `json:"grpc_codes,omitempty"`
+	ErrorClasses  map[string]*ErrorClassStats `json:"error_classes,omitempty"`
+	ErrorRate     float64                     `json:"error_rate"`
+
+	AssertionFailed   int            `json:"assertion_failed,omitempty"`
+	AssertionFailures map[string]int `json:"assertion_failures,omitempty"`
+
+	Latency LatencySummary `json:"latency"`
+}
+
+// ConnectionSummary reports connection reuse and mean phase timings in
+// milliseconds.
+type ConnectionSummary struct {
+	NewConnections int            `json:"new_connections"`
+	Protocols      map[string]int `json:"protocols"`
+	DNSMs          float64        `json:"dns_mean_ms"`
+	ConnectMs      float64        `json:"connect_mean_ms"`
+	TLSMs          float64        `json:"tls_mean_ms"`
+	TTFBMs         float64        `json:"ttfb_mean_ms"`
+}
+
+type StepSummary struct {
+	Name string `json:"name"`
+	StatsSummary
+}
+
+// LatencySummary holds latency figures in milliseconds. Histogram keeps the
+// full distribution (in nanoseconds) so runs can be compared later.
+type LatencySummary struct {
+	MinMs       float64            `json:"min_ms"`
+	MeanMs      float64            `json:"mean_ms"`
+	MaxMs       float64            `json:"max_ms"`
+	Percentiles map[string]float64 `json:"percentiles_ms"`
+	Histogram   *Histogram         `json:"histogram,omitempty"`
+}
+
+func newSummary(cfg LoadConfig, startedAt time.Time, totalDuration time.Duration, stats *Stats) Summary {
+	summary := Summary{
+		Concurrency:       cfg.Concurrency,
+		TargetRate:        cfg.Rate,
+		StartedAt:         startedAt,
+		DurationSeconds:   totalDuration.Seconds(),
+		RequestsPerSecond: stats.RequestsPerSecond(totalDuration),
+		StatsSummary:      newStatsSummary(stats),
+		Connections: ConnectionSummary{
+			NewConnections: stats.NewConnections,
+			Protocols:      stats.Protocols,
+			DNSMs:          milliseconds(stats.DNS.Mean()),
+			ConnectMs:      milliseconds(stats.Connect.Mean()),
+			TLSMs:          milliseconds(stats.TLS.Mean()),
+			TTFBMs:         milliseconds(stats.TTFB.Mean()),
+		},
+	}
+	for _, name := range stats.StepNames {
+		summary.Steps = append(summary.Steps, StepSummary{
+			Name:         name,
+			StatsSummary: newStatsSummary(stats.Steps[name]),
+		})
+	}
+	return summary
+}
+
+func newStatsSummary(stats *Stats) StatsSummary {
+	return StatsSummary{
+		TotalRequests: stats.TotalRequests,
+		SuccessCount:  stats.SuccessCount,
+		FailedCount:   stats.FailedCount,
+		StatusCodes:   stats.StatusCodes,
+		GRPCCodes:     stats.GRPCCodes,
+		ErrorClasses:  stats.ErrorClasses,
+		ErrorRate:     stats.ratio("error_rate"),
+
+		AssertionFailed:   stats.AssertionFailed,
+		AssertionFailures: stats.AssertionFailures,
+
+		Latency: newLatencySummary(stats.Latency),
+	}
+}
+
+func newLatencySummary(h *Histogram) LatencySummary {
+	percentiles := make(map[string]float64, len(reportPercentiles))
+	for _, p := range reportPercentiles {
+		percentiles[percentileName(p)] = milliseconds(h.Percentile(p))
+	}
+	return LatencySummary{
+		MinMs:       milliseconds(h.Min()),
+		MeanMs:      milliseconds(h.Mean()),
+		MaxMs:       milliseconds(h.Max()),
+		Percentiles: percentiles,
+		Histogram:   h,
+	}
+}
+
+func percentileName(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// OutputWriter receives every result while the test runs and the summary
+// once it finishes.
+type OutputWriter interface {
+	WriteResult(result Result) error
+	Close(summary Summary) error
+}
+
+func NewOutputWriter(format string, w io.Writer) (OutputWriter, error) {
+	switch format {
+	case "json":
+		return &jsonOutput{w: w}, nil
+	case "csv":
+		return newCSVOutput(w)
+	case "junit":
+		return &junitOutput{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (expected json, csv or junit)", format)
+	}
+}
+
+type jsonOutput struct {
+	w io.Writer
+}
+
+func (o *jsonOutput) WriteResult(Result) error {
+	return nil
+}
+
+func (o *jsonOutput) Close(summary Summary) error {
+	encoder := json.NewEncoder(o.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+// csvOutput streams one row per request so large runs are not buffered.
+type csvOutput struct {
+	w *csv.Writer
+}
+
+func newCSVOutput(w io.Writer) (*csvOutput, error) {
+	o := &csvOutput{w: csv.NewWriter(w)}
+	if err := o.w.Write([]string{"timestamp", "status", "latency_ms", "error", "error_class", "assertion_failures"}); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *csvOutput) WriteResult(result Result) error {
+	var errMsg, errClass string
+	if result.Error != nil {
+		errMsg = result.Error.Error()
+		errClass = classifyError(result.Error)
+	}
+	status := strconv.Itoa(result.StatusCode)
+	if result.Proto == "grpc" {
+		status = result.GRPCCode.String()
+	}
+	return o.w.Write([]string{
+		result.Timestamp.Format(time.RFC3339Nano),
+		status,
+		strconv.FormatFloat(milliseconds(result.Duration), 'f', 3, 64),
+		errMsg,
+		errClass,
+		strings.Join(result.AssertionFailures, "; "),
+	})
+}
+
+func (o *csvOutput) Close(Summary) error {
+	o.w.Flush()
+	return o.w.Error()
+}
+
+type junitOutput struct {
+	w io.Writer
+}
+
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (o *junitOutput) WriteResult(Result) error {
+	return nil
+}
+
+func (o *junitOutput) Close(summary Summary) error {
+	duration := strconv.FormatFloat(summary.DurationSeconds, 'f', 3, 64)
+	target := summary.URL
+	if summary.Scenario != "" {
+		target = summary.Scenario
+	}
+	requests := junitTestCase{
+		Name:      "requests",
+		ClassName: target,
+		Time:      duration,
+	}
+	if summary.FailedCount > 0 || summary.AssertionFailed > 0 {
+		requests.Failure = &junitFailure{
+			Message: fmt.Sprintf("%d of %d requests failed, %d failed assertions", summary.FailedCount, summary.TotalRequests, summary.AssertionFailed),
+		}
+	}
+
+	suite := junitTestSuite{
+		Name:      "stress-test",
+		Time:      duration,
+		Timestamp: summary.StartedAt.Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "target", Value: target},
+			{Name: "total_requests", Value: strconv.Itoa(summary.TotalRequests)},
+			{Name: "requests_per_second", Value: strconv.FormatFloat(summary.RequestsPerSecond, 'f', 2, 64)},
+			{Name: "latency_mean_ms", Value: strconv.FormatFloat(summary.Latency.MeanMs, 'f', 3, 64)},
+		},
+		TestCases: []junitTestCase{requests},
+	}
+	for _, threshold := range summary.Thresholds {
+		tc := junitTestCase{
+			Name:      "threshold " + threshold.Expression,
+			ClassName: target,
+			Time:      duration,
+		}
+		if !threshold.Passed {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("threshold %s failed (actual: %s)", threshold.Expression, threshold.Actual),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	for _, p := range reportPercentiles {
+		name := percentileName(p)
+		suite.Properties = append(suite.Properties, junitProperty{
+			Name:  "latency_" + name + "_ms",
+			Value: strconv.FormatFloat(summary.Latency.Percentiles[name], 'f', 3, 64),
+		})
+	}
+	for _, tc := range suite.TestCases {
+		suite.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(o.w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(o.w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(o.w, "\n")
+	return err
+}
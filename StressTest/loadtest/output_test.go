@@ -0,0 +1,133 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func testSummary() Summary {
+	stats := NewStats()
+	for i := 0; i < 9; i++ {
+		stats.Add(Result{StatusCode: 200, Duration: time.Duration(i+1) * 10 * time.Millisecond})
+	}
+	stats.Add(Result{Error: context.DeadlineExceeded, Duration: time.Second})
+
+	startedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	summary := newSummary(LoadConfig{Concurrency: 2}, startedAt, 2*time.Second, stats)
+	summary.URL = "http://localhost:8080"
+	summary.Thresholds = []ThresholdResult{
+		{Expression: "p95<2s", Actual: "1s", Passed: true},
+		{Expression: "error_rate<1%", Actual: "10.00%", Passed: false},
+	}
+	return summary
+}
+
+func TestCSVOutputColumns(t *testing.T) {
+	var buf bytes.Buffer
+	output, err := NewOutputWriter("csv", &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	results := []Result{
+		{Timestamp: timestamp, StatusCode: 200, Duration: 12500 * time.Microsecond,
+			AssertionFailures: []string{"status == 201", "body contains ok"}},
+		{Timestamp: timestamp, Error: context.DeadlineExceeded, Duration: time.Second},
+		{Timestamp: timestamp, Proto: "grpc", GRPCCode: codes.Unavailable, Duration: time.Millisecond},
+	}
+	for _, result := range results {
+		if err := output.WriteResult(result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := output.Close(Summary{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	want := [][]string{
+		{"timestamp", "status", "latency_ms", "error", "error_class", "assertion_failures"},
+		{"2026-10-19T12:00:00Z", "200", "12.500", "", "", "status == 201; body contains ok"},
+		{"2026-10-19T12:00:00Z", "0", "1000.000", "context deadline exceeded", ErrClassTimeout, ""},
+		{"2026-10-19T12:00:00Z", "Unavailable", "1.000", "", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("unexpected rows:\n got %q\nwant %q", rows, want)
+	}
+}
+
+func TestJSONOutputRoundTrip(t *testing.T) {
+	summary := testSummary()
+	var buf bytes.Buffer
+	output, _ := NewOutputWriter("json", &buf)
+	if err := output.Close(summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded Summary
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if decoded.URL != summary.URL || !decoded.StartedAt.Equal(summary.StartedAt) ||
+		decoded.TotalRequests != 10 || decoded.FailedCount != 1 || decoded.ErrorRate != summary.ErrorRate {
+		t.Errorf("unexpected summary: %+v", decoded)
+	}
+	if !reflect.DeepEqual(decoded.StatusCodes, summary.StatusCodes) {
+		t.Errorf("expected status codes %v, got %v", summary.StatusCodes, decoded.StatusCodes)
+	}
+	if decoded.ErrorClasses[ErrClassTimeout] == nil || decoded.ErrorClasses[ErrClassTimeout].Count != 1 {
+		t.Errorf("expected one timeout in the error classes, got %v", decoded.ErrorClasses)
+	}
+	if !reflect.DeepEqual(decoded.Latency.Percentiles, summary.Latency.Percentiles) {
+		t.Errorf("expected percentiles %v, got %v", summary.Latency.Percentiles, decoded.Latency.Percentiles)
+	}
+	if decoded.Latency.Histogram == nil ||
+		decoded.Latency.Histogram.Percentile(95) != summary.Latency.Histogram.Percentile(95) {
+		t.Error("expected the histogram to survive the round trip")
+	}
+	if !reflect.DeepEqual(decoded.Thresholds, summary.Thresholds) {
+		t.Errorf("expected thresholds %v, got %v", summary.Thresholds, decoded.Thresholds)
+	}
+}
+
+func TestJUnitOutputIsWellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	output, _ := NewOutputWriter("junit", &buf)
+	if err := output.Close(testSummary()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Error("expected an XML declaration")
+	}
+
+	var suite junitTestSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+	if suite.Name != "stress-test" || suite.Tests != 3 || suite.Failures != 2 || suite.Time != "2.000" {
+		t.Errorf("unexpected suite: %+v", suite)
+	}
+	failed := map[string]bool{}
+	for _, tc := range suite.TestCases {
+		if tc.ClassName != "http://localhost:8080" {
+			t.Errorf("%s: unexpected class name %q", tc.Name, tc.ClassName)
+		}
+		failed[tc.Name] = tc.Failure != nil
+	}
+	want := map[string]bool{"requests": true, "threshold p95<2s": false, "threshold error_rate<1%": true}
+	if !reflect.DeepEqual(failed, want) {
+		t.Errorf("expected test cases %v, got %v", want, failed)
+	}
+}
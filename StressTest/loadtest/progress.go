@@ -0,0 +1,152 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Progress tracks interim statistics for the live status line. Rolling
+// figures cover the interval since the previous line.
+type Progress struct {
+	mu        sync.Mutex
+	cfg       LoadConfig
+	start     time.Time
+	completed int
+	errors    map[string]int
+	window    *Histogram
+	windowN   int
+	lastTick  time.Time
+}
+
+func NewProgress(cfg LoadConfig, start time.Time) *Progress {
+	return &Progress{
+		cfg:      cfg,
+		start:    start,
+		errors:   make(map[string]int),
+		window:   NewHistogram(),
+		lastTick: start,
+	}
+}
+
+func (p *Progress) Add(result Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed++
+	p.windowN++
+	if class := errorClass(result); class != "" {
+		p.errors[class]++
+	}
+	if result.Error == nil {
+		p.window.Record(result.Duration)
+	}
+}
+
+// AddStats counts a batch of already aggregated results, such as the
+// partial stats streamed by distributed agents.
+func (p *Progress) AddStats(delta *Stats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed += delta.TotalRequests
+	p.windowN += delta.TotalRequests
+	for class, count := range delta.errorCounts() {
+		p.errors[class] += count
+	}
+	p.window.Merge(delta.Latency)
+}
+
+// errorCounts counts the failed results of aggregated stats by the classes
+// errorClass assigns to single results.
+func (s *Stats) errorCounts() map[string]int {
+	counts := make(map[string]int)
+	for class, e := range s.ErrorClasses {
+		counts[class] += e.Count
+	}
+	for code, count := range s.StatusCodes {
+		if class := errorClass(Result{StatusCode: code}); class != "" {
+			counts[class] += count
+		}
+	}
+	for name, count := range s.GRPCCodes {
+		if name != codes.OK.String() {
+			counts["grpc_"+name] += count
+		}
+	}
+	if s.AssertionFailed > 0 {
+		counts[ErrClassAssertion] += s.AssertionFailed
+	}
+	return counts
+}
+
+// Line renders the status line and starts a new rolling window.
+func (p *Progress) Line(now time.Time) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elapsed := now.Sub(p.start)
+	var b strings.Builder
+	if p.cfg.Requests > 0 {
+		fmt.Fprintf(&b, "%d/%d", p.completed, p.cfg.Requests)
+	} else {
+		fmt.Fprintf(&b, "%d", p.completed)
+	}
+	fmt.Fprintf(&b, " | %v", elapsed.Round(time.Second))
+
+	var rps float64
+	if interval := now.Sub(p.lastTick); interval > 0 {
+		rps = float64(p.windowN) / interval.Seconds()
+	}
+	fmt.Fprintf(&b, " | %.1f rps", rps)
+	if p.window.Count() > 0 {
+		fmt.Fprintf(&b, " | p50 %v p99 %v", round(p.window.Percentile(50)), round(p.window.Percentile(99)))
+	}
+
+	if len(p.errors) > 0 {
+		classes := make([]string, 0, len(p.errors))
+		for class := range p.errors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		parts := make([]string, len(classes))
+		for i, class := range classes {
+			parts[i] = fmt.Sprintf("%s=%d", class, p.errors[class])
+		}
+		fmt.Fprintf(&b, " | errors %s", strings.Join(parts, " "))
+	}
+
+	if eta, ok := p.eta(elapsed); ok {
+		fmt.Fprintf(&b, " | ETA %v", eta.Round(time.Second))
+	}
+
+	p.window.Reset()
+	p.windowN = 0
+	p.lastTick = now
+	return b.String()
+}
+
+// eta estimates the remaining time from the request count and/or the
+// configured duration, whichever ends the run first.
+func (p *Progress) eta(elapsed time.Duration) (time.Duration, bool) {
+	var eta time.Duration
+	known := false
+	if p.cfg.Requests > 0 && p.completed > 0 {
+		perRequest := elapsed / time.Duration(p.completed)
+		eta = perRequest * time.Duration(p.cfg.Requests-p.completed)
+		known = true
+	}
+	if d := p.cfg.RunDuration(); d > 0 {
+		remaining := d - elapsed
+		if remaining < 0 {
+			remaining = 0
+		}
+		if !known || remaining < eta {
+			eta = remaining
+		}
+		known = true
+	}
+	return eta, known
+}
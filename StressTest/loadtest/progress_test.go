@@ -0,0 +1,28 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	progress := NewProgress(LoadConfig{Requests: 10, Concurrency: 2}, start)
+	for i := 0; i < 3; i++ {
+		progress.Add(Result{StatusCode: 200, Duration: 100 * time.Millisecond})
+	}
+	progress.Add(Result{StatusCode: 503, Duration: 100 * time.Millisecond})
+	progress.Add(Result{Error: context.DeadlineExceeded, Duration: time.Second})
+
+	want := "5/10 | 5s | 1.0 rps | p50 100ms p99 100ms | errors 5xx=1 timeout=1 | ETA 5s"
+	if got := progress.Line(start.Add(5 * time.Second)); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// The rolling figures start over while the totals carry on
+	want = "5/10 | 6s | 0.0 rps | errors 5xx=1 timeout=1 | ETA 6s"
+	if got := progress.Line(start.Add(6 * time.Second)); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
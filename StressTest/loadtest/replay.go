@@ -0,0 +1,216 @@
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReplayEntry is one recorded request. Offset is its send time relative to
+// the first request of the recording.
+type ReplayEntry struct {
+	Offset  time.Duration
+	Method  string
+	Target  string // path and query
+	Headers http.Header
+	Body    []byte
+}
+
+// LoadReplay reads a HAR file (detected by a .har extension or JSON content)
+// or an access log in common or combined format.
+func LoadReplay(file string) ([]ReplayEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ReplayEntry
+	if strings.EqualFold(path.Ext(file), ".har") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		entries, err = parseHAR(data)
+	} else {
+		entries, err = parseAccessLog(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("reading %s: no requests found", file)
+	}
+	return entries, nil
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					Text string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// replaySkippedHeaders are set by the client for the new target rather than
+// copied from the recording.
+var replaySkippedHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Accept-Encoding":   true,
+	"Upgrade":           true,
+}
+
+func parseHAR(data []byte) ([]ReplayEntry, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+	var entries []ReplayEntry
+	var first time.Time
+	for i, e := range har.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if first.IsZero() || e.StartedDateTime.Before(first) {
+			first = e.StartedDateTime
+		}
+		entry := ReplayEntry{
+			Offset:  time.Duration(e.StartedDateTime.UnixNano()),
+			Method:  e.Request.Method,
+			Target:  u.RequestURI(),
+			Headers: make(http.Header),
+		}
+		for _, h := range e.Request.Headers {
+			name := http.CanonicalHeaderKey(h.Name)
+			if strings.HasPrefix(name, ":") || replaySkippedHeaders[name] {
+				continue
+			}
+			entry.Headers.Add(name, h.Value)
+		}
+		if e.Request.PostData != nil {
+			entry.Body = []byte(e.Request.PostData.Text)
+		}
+		entries = append(entries, entry)
+	}
+	for i := range entries {
+		entries[i].Offset -= time.Duration(first.UnixNano())
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Offset < entries[j].Offset })
+	return entries, nil
+}
+
+// accessLogLine matches the common log format, optionally followed by the
+// referer and user agent of the combined format.
+var accessLogLine = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*" \d{3} \S+(?: "([^"]*)" "([^"]*)")?`)
+
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+func parseAccessLog(data []byte) ([]ReplayEntry, error) {
+	var entries []ReplayEntry
+	var first time.Time
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		m := accessLogLine.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: not in common or combined log format", line)
+		}
+		at, err := time.Parse(accessLogTime, m[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if first.IsZero() {
+			first = at
+		}
+		entry := ReplayEntry{Offset: at.Sub(first), Method: m[2], Target: m[3], Headers: make(http.Header)}
+		if m[4] != "" && m[4] != "-" {
+			entry.Headers.Set("Referer", m[4])
+		}
+		if m[5] != "" && m[5] != "-" {
+			entry.Headers.Set("User-Agent", m[5])
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Logs are written when requests finish, so lines may be slightly out
+	// of order
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Offset < entries[j].Offset })
+	if len(entries) > 0 && entries[0].Offset < 0 {
+		shift := entries[0].Offset
+		for i := range entries {
+			entries[i].Offset -= shift
+		}
+	}
+	return entries, nil
+}
+
+// ReplaySchedule returns the send offsets of the entries played at speed
+// times the original pace.
+func ReplaySchedule(entries []ReplayEntry, speed float64) []time.Duration {
+	schedule := make([]time.Duration, len(entries))
+	for i, e := range entries {
+		schedule[i] = time.Duration(float64(e.Offset) / speed)
+	}
+	return schedule
+}
+
+// ReplayTarget sends the recorded requests against a base URL. Job i replays
+// entry i, wrapping around when there are more jobs than entries.
+type ReplayTarget struct {
+	base       *url.URL
+	entries    []ReplayEntry
+	assertions Assertions
+}
+
+func NewReplayTarget(baseURL string, entries []ReplayEntry, assertions Assertions) (*ReplayTarget, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+	return &ReplayTarget{base: base, entries: entries, assertions: assertions}, nil
+}
+
+func (t *ReplayTarget) Run(client *http.Client, j Job, results chan<- Result) {
+	startTime := jobStart(j)
+	entry := t.entries[j.Seq%len(t.entries)]
+	req, err := http.NewRequest(entry.Method, t.url(entry.Target), bytes.NewReader(entry.Body))
+	if err != nil {
+		results <- Result{Timestamp: startTime, Duration: time.Since(startTime), Error: fmt.Errorf("building request: %w", err)}
+		return
+	}
+	for name, values := range entry.Headers {
+		req.Header[name] = values
+	}
+	result, _ := doRequest(client, req, startTime, false, t.assertions)
+	results <- result
+}
+
+// url joins the recorded path to the base URL, keeping a base path prefix.
+func (t *ReplayTarget) url(target string) string {
+	return strings.TrimSuffix(t.base.Scheme+"://"+t.base.Host+t.base.Path, "/") + "/" + strings.TrimPrefix(target, "/")
+}
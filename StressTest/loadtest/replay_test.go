@@ -0,0 +1,88 @@
+package loadtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseAccessLog(t *testing.T) {
+	data := []byte(`127.0.0.1 - - [19/Oct/2026:10:00:00 +0000] "GET /a?x=1 HTTP/1.1" 200 12 "-" "curl/8.0"
+127.0.0.1 - frank [19/Oct/2026:10:00:02 +0000] "GET /b HTTP/1.1" 404 0
+127.0.0.1 - - [19/Oct/2026:10:00:01 +0000] "POST /c HTTP/1.1" 201 5 "http://ref/" "Mozilla/5.0"
+`)
+	entries, err := parseAccessLog(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		offset time.Duration
+		method string
+		target string
+	}{
+		{0, "GET", "/a?x=1"},
+		{time.Second, "POST", "/c"},
+		{2 * time.Second, "GET", "/b"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Offset != w.offset || e.Method != w.method || e.Target != w.target {
+			t.Errorf("entry %d: expected %v %s %s, got %v %s %s", i, w.offset, w.method, w.target, e.Offset, e.Method, e.Target)
+		}
+	}
+	if entries[1].Headers.Get("Referer") != "http://ref/" || entries[0].Headers.Get("User-Agent") != "curl/8.0" {
+		t.Errorf("expected referer and user agent headers, got %v and %v", entries[1].Headers, entries[0].Headers)
+	}
+
+	if _, err := parseAccessLog([]byte("not a log line\n")); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+}
+
+func TestReplayTargetSendsHAREntriesToBaseURL(t *testing.T) {
+	har := []byte(`{"log":{"entries":[
+{"startedDateTime":"2026-10-19T10:00:00.500Z","request":{"method":"POST","url":"https://prod/api/items","headers":[{"name":"Content-Type","value":"application/json"},{"name":"Host","value":"prod"}],"postData":{"text":"{\"name\":\"x\"}"}}},
+{"startedDateTime":"2026-10-19T10:00:00.000Z","request":{"method":"GET","url":"https://prod/api/items?page=2","headers":[{"name":":authority","value":"prod"}]}}
+]}}`)
+	entries, err := parseHAR(har)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries[0].Method != "GET" || entries[1].Offset != 500*time.Millisecond {
+		t.Fatalf("expected entries sorted by start time, got %+v", entries)
+	}
+
+	type received struct{ method, uri, contentType, body string }
+	got := make(chan received, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.Method, r.RequestURI, r.Header.Get("Content-Type"), string(body)}
+	}))
+	defer server.Close()
+
+	target, err := NewReplayTarget(server.URL+"/staging/", entries, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results := make(chan Result, 2)
+	for i := range entries {
+		target.Run(server.Client(), Job{Seq: i}, results)
+		if result := <-results; result.Error != nil || result.StatusCode != 200 {
+			t.Fatalf("request %d: unexpected result %+v", i, result)
+		}
+	}
+	want := []received{
+		{"GET", "/staging/api/items?page=2", "", ""},
+		{"POST", "/staging/api/items", "application/json", `{"name":"x"}`},
+	}
+	for i, w := range want {
+		if r := <-got; r != w {
+			t.Errorf("request %d: expected %+v, got %+v", i, w, r)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Stats aggregates results as they arrive so the report does not depend on
+// keeping every Result in memory.
+type Stats struct {
+	TotalRequests int
+	SuccessCount  int
+	FailedCount   int
+	StatusCodes   map[int]int
+	GRPCCodes     map[string]int
+	ErrorClasses  map[string]*ErrorClassStats
+	Latency       *Histogram
+
+	// Responses checked against assertions, and the failures by assertion.
+	// With assertions a response is successful when it passes all of them
+	Asserted          bool
+	AssertionFailed   int
+	AssertionFailures map[string]int
+
+	// Connection reuse and phase timings from httptrace
+	NewConnections int
+	Protocols      map[string]int
+	DNS            *Histogram
+	Connect        *Histogram
+	TLS            *Histogram
+	TTFB           *Histogram
+
+	// Steps holds per-step stats of scenario runs, in order of first use
+	Steps     map[string]*Stats
+	StepNames []string
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		StatusCodes:  make(map[int]int),
+		GRPCCodes:    make(map[string]int),
+		ErrorClasses: make(map[string]*ErrorClassStats),
+		Latency:      NewHistogram(),
+
+		AssertionFailures: make(map[string]int),
+		Protocols:         make(map[string]int),
+		DNS:               NewHistogram(),
+		Connect:           NewHistogram(),
+		TLS:               NewHistogram(),
+		TTFB:              NewHistogram(),
+	}
+}
+
+func (s *Stats) Add(result Result) {
+	s.add(result)
+	if result.Step == "" {
+		return
+	}
+	if s.Steps == nil {
+		s.Steps = make(map[string]*Stats)
+	}
+	step, ok := s.Steps[result.Step]
+	if !ok {
+		step = NewStats()
+		s.Steps[result.Step] = step
+		s.StepNames = append(s.StepNames, result.Step)
+	}
+	step.add(result)
+}
+
+func (s *Stats) add(result Result) {
+	s.TotalRequests++
+	s.addTrace(result.Trace)
+	if result.Error != nil {
+		s.FailedCount++
+		class := classifyError(result.Error)
+		if s.ErrorClasses[class] == nil {
+			s.ErrorClasses[class] = &ErrorClassStats{}
+		}
+		s.ErrorClasses[class].add(result.Error)
+		return
+	}
+	s.Latency.Record(result.Duration)
+	s.Protocols[result.Proto]++
+	if result.Proto == "grpc" {
+		s.GRPCCodes[result.GRPCCode.String()]++
+	} else {
+		s.StatusCodes[result.StatusCode]++
+	}
+	if result.Asserted {
+		s.Asserted = true
+		if len(result.AssertionFailures) > 0 {
+			s.AssertionFailed++
+		}
+		for _, expression := range result.AssertionFailures {
+			s.AssertionFailures[expression]++
+		}
+	}
+	if succeeded(result) {
+		s.SuccessCount++
+	}
+}
+
+// succeeded tells whether a response counts as successful: it passed its
+// assertions or, without any, has status 200 (gRPC: OK).
+func succeeded(result Result) bool {
+	switch {
+	case result.Asserted:
+		return len(result.AssertionFailures) == 0
+	case result.Proto == "grpc":
+		return result.GRPCCode == codes.OK
+	default:
+		return result.StatusCode == 200
+	}
+}
+
+func (s *Stats) addTrace(trace ConnTrace) {
+	if trace.NewConn {
+		s.NewConnections++
+	}
+	if trace.DNS > 0 {
+		s.DNS.Record(trace.DNS)
+	}
+	if trace.Connect > 0 {
+		s.Connect.Record(trace.Connect)
+	}
+	if trace.TLS > 0 {
+		s.TLS.Record(trace.TLS)
+	}
+	if trace.TTFB > 0 {
+		s.TTFB.Record(trace.TTFB)
+	}
+}
+
+// Merge adds the stats of other to s, as when combining the partial results
+// of distributed agents.
+func (s *Stats) Merge(other *Stats) {
+	s.merge(other)
+	for _, name := range other.StepNames {
+		if s.Steps == nil {
+			s.Steps = make(map[string]*Stats)
+		}
+		step, ok := s.Steps[name]
+		if !ok {
+			step = NewStats()
+			s.Steps[name] = step
+			s.StepNames = append(s.StepNames, name)
+		}
+		step.merge(other.Steps[name])
+	}
+}
+
+func (s *Stats) merge(other *Stats) {
+	s.TotalRequests += other.TotalRequests
+	s.SuccessCount += other.SuccessCount
+	s.FailedCount += other.FailedCount
+	s.NewConnections += other.NewConnections
+	s.Asserted = s.Asserted || other.Asserted
+	s.AssertionFailed += other.AssertionFailed
+	for expression, count := range other.AssertionFailures {
+		s.AssertionFailures[expression] += count
+	}
+	for code, count := range other.StatusCodes {
+		s.StatusCodes[code] += count
+	}
+	for name, count := range other.GRPCCodes {
+		s.GRPCCodes[name] += count
+	}
+	for proto, count := range other.Protocols {
+		s.Protocols[proto] += count
+	}
+	for class, errClass := range other.ErrorClasses {
+		if s.ErrorClasses[class] == nil {
+			s.ErrorClasses[class] = &ErrorClassStats{}
+		}
+		s.ErrorClasses[class].merge(errClass)
+	}
+	s.Latency.Merge(other.Latency)
+	s.DNS.Merge(other.DNS)
+	s.Connect.Merge(other.Connect)
+	s.TLS.Merge(other.TLS)
+	s.TTFB.Merge(other.TTFB)
+}
+
+func (s *Stats) RequestsPerSecond(totalDuration time.Duration) float64 {
+	if totalDuration <= 0 {
+		return 0
+	}
+	return float64(s.TotalRequests) / totalDuration.Seconds()
+}
+
+var reportPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+// printReport prints the report; breakdown labels the per-step (or
+// per-target) sections.
+func printReport(w io.Writer, stats *Stats, totalDuration time.Duration, breakdown string) {
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintln(w, "Stress Test Report")
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintf(w, "Total time: %v\n", totalDuration)
+	fmt.Fprintf(w, "Total requests: %d\n", stats.TotalRequests)
+	fmt.Fprintf(w, "Requests per second: %.2f\n", stats.RequestsPerSecond(totalDuration))
+	printStatus(w, stats)
+	printLatency(w, stats.Latency)
+	printConnections(w, stats)
+	for _, name := range stats.StepNames {
+		step := stats.Steps[name]
+		fmt.Fprintln(w, "--------------------------------------------------")
+		fmt.Fprintf(w, "%s: %s\n", breakdown, name)
+		fmt.Fprintf(w, "Total requests: %d\n", step.TotalRequests)
+		printStatus(w, step)
+		printPercentiles(w, step.Latency)
+	}
+	fmt.Fprintln(w, "--------------------------------------------------")
+}
+
+func printStatus(w io.Writer, stats *Stats) {
+	switch {
+	case stats.Asserted:
+		fmt.Fprintf(w, "Successful requests (assertions passed): %d\n", stats.SuccessCount)
+	case len(stats.GRPCCodes) > 0:
+		fmt.Fprintf(w, "Successful requests (gRPC OK): %d\n", stats.SuccessCount)
+	default:
+		fmt.Fprintf(w, "Successful requests (HTTP 200): %d\n", stats.SuccessCount)
+	}
+	if len(stats.GRPCCodes) > 0 {
+		fmt.Fprintln(w, "gRPC status code distribution:")
+		names := make([]string, 0, len(stats.GRPCCodes))
+		for name := range stats.GRPCCodes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %d\n", name, stats.GRPCCodes[name])
+		}
+	}
+	if len(stats.StatusCodes) > 0 || len(stats.GRPCCodes) == 0 {
+		fmt.Fprintln(w, "Status code distribution:")
+		statusCodes := make([]int, 0, len(stats.StatusCodes))
+		for code := range stats.StatusCodes {
+			statusCodes = append(statusCodes, code)
+		}
+		sort.Ints(statusCodes)
+		for _, code := range statusCodes {
+			fmt.Fprintf(w, "  HTTP %d: %d\n", code, stats.StatusCodes[code])
+		}
+	}
+	if stats.AssertionFailed > 0 {
+		fmt.Fprintf(w, "Failed assertions: %d responses\n", stats.AssertionFailed)
+		expressions := make([]string, 0, len(stats.AssertionFailures))
+		for expression := range stats.AssertionFailures {
+			expressions = append(expressions, expression)
+		}
+		sort.Strings(expressions)
+		for _, expression := range expressions {
+			fmt.Fprintf(w, "  %s: %d\n", expression, stats.AssertionFailures[expression])
+		}
+	}
+	if stats.FailedCount > 0 {
+		fmt.Fprintf(w, "Failed requests: %d\n", stats.FailedCount)
+		classes := make([]string, 0, len(stats.ErrorClasses))
+		for class := range stats.ErrorClasses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			errClass := stats.ErrorClasses[class]
+			fmt.Fprintf(w, "  %s: %d\n", class, errClass.Count)
+			for _, sample := range errClass.Samples {
+				fmt.Fprintf(w, "    e.g. %s\n", sample)
+			}
+		}
+	}
+}
+
+func printLatency(w io.Writer, h *Histogram) {
+	if h.Count() == 0 {
+		return
+	}
+	printPercentiles(w, h)
+	fmt.Fprintln(w, "Latency histogram:")
+	for _, line := range histogramLines(h, 10, 40) {
+		fmt.Fprintln(w, "  "+line)
+	}
+}
+
+func printConnections(w io.Writer, stats *Stats) {
+	// gRPC calls are not traced
+	if stats.NewConnections == 0 && stats.TTFB.Count() == 0 {
+		return
+	}
+	fmt.Fprintf(w, "New connections: %d\n", stats.NewConnections)
+	protocols := make([]string, 0, len(stats.Protocols))
+	for proto := range stats.Protocols {
+		protocols = append(protocols, proto)
+	}
+	sort.Strings(protocols)
+	for _, proto := range protocols {
+		fmt.Fprintf(w, "  %s: %d\n", proto, stats.Protocols[proto])
+	}
+	phases := []struct {
+		name string
+		h    *Histogram
+	}{
+		{"DNS", stats.DNS},
+		{"Connect", stats.Connect},
+		{"TLS", stats.TLS},
+		{"TTFB", stats.TTFB},
+	}
+	printed := false
+	for _, phase := range phases {
+		if phase.h.Count() == 0 {
+			continue
+		}
+		if !printed {
+			fmt.Fprintln(w, "Connection phases (count / mean / p99):")
+			printed = true
+		}
+		fmt.Fprintf(w, "  %-8s %d / %v / %v\n", phase.name, phase.h.Count(), round(phase.h.Mean()), round(phase.h.Percentile(99)))
+	}
+}
+
+func printPercentiles(w io.Writer, h *Histogram) {
+	printNamedPercentiles(w, "Latency", h)
+}
+
+func printNamedPercentiles(w io.Writer, name string, h *Histogram) {
+	if h.Count() == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", name)
+	fmt.Fprintf(w, "  min: %v  mean: %v  max: %v\n", round(h.Min()), round(h.Mean()), round(h.Max()))
+	for _, p := range reportPercentiles {
+		fmt.Fprintf(w, "  p%-5g %v\n", p, round(h.Percentile(p)))
+	}
+}
+
+// histogramLines renders the latency distribution as rows of equal-width
+// latency bins between min and max.
+func histogramLines(h *Histogram, bins, width int) []string {
+	low, step, counts := histogramBins(h, bins)
+	if step <= 0 {
+		return []string{fmt.Sprintf("%10v [%d] %s", round(low), h.Count(), strings.Repeat("■", width))}
+	}
+
+	var peak int64
+	for _, c := range counts {
+		if c > peak {
+			peak = c
+		}
+	}
+
+	lines := make([]string, 0, bins)
+	for i, c := range counts {
+		bar := int(c * int64(width) / peak)
+		lines = append(lines, fmt.Sprintf("%10v [%d] %s", round(low+step*time.Duration(i+1)), c, strings.Repeat("■", bar)))
+	}
+	return lines
+}
+
+// histogramBins splits the range between min and max into equal-width bins.
+// step is zero when all samples have the same value.
+func histogramBins(h *Histogram, bins int) (time.Duration, time.Duration, []int64) {
+	low, high := h.Min(), h.Max()
+	step := (high - low) / time.Duration(bins)
+	if step <= 0 {
+		return low, 0, []int64{h.Count()}
+	}
+
+	counts := make([]int64, bins)
+	h.ForEach(func(value time.Duration, count int64) {
+		bin := int((value - low) / step)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		counts[bin] += count
+	})
+	return low, step, counts
+}
+
+// round trims latencies to a readable precision.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+func PrintThresholds(w io.Writer, results []ThresholdResult) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Thresholds:")
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "  [%s] %s (actual: %s)\n", status, r.Expression, r.Actual)
+	}
+	fmt.Fprintln(w, "--------------------------------------------------")
+}
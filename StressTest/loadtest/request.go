@@ -0,0 +1,211 @@
+package loadtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RequestSpec is the raw description of the request sent by every job.
+// URL, header values and body may contain text/template actions.
+type RequestSpec struct {
+	Method     string
+	URL        string
+	Headers    []string
+	Body       string
+	DataFile   string
+	Assertions Assertions
+}
+
+// templateData is what request templates can reference:
+//
+//	{{.Seq}}            sequence number of the request
+//	{{uuid}}            random UUID
+//	{{randInt 1 100}}   random integer in [1, 100]
+//	{{.Row.email}}      column of the data file row for this request
+//	{{.Vars.id}}        value extracted by an earlier scenario step
+type templateData struct {
+	Seq  int
+	Row  map[string]string
+	Vars map[string]string
+}
+
+type headerTemplate struct {
+	name  string
+	value *template.Template
+}
+
+// RequestTemplate builds the concrete request for each sequence number.
+type RequestTemplate struct {
+	method     string
+	url        *template.Template
+	headers    []headerTemplate
+	body       *template.Template
+	rows       []map[string]string
+	assertions Assertions
+}
+
+var templateFuncs = template.FuncMap{
+	"uuid":    newUUID,
+	"randInt": randInt,
+}
+
+func NewRequestTemplate(spec RequestSpec) (*RequestTemplate, error) {
+	t := &RequestTemplate{method: strings.ToUpper(spec.Method), assertions: spec.Assertions}
+	if t.method == "" {
+		t.method = http.MethodGet
+	}
+
+	var err error
+	if t.url, err = parseTemplate("url", spec.URL); err != nil {
+		return nil, err
+	}
+	for _, header := range spec.Headers {
+		name, value, _ := strings.Cut(header, ":")
+		tmpl, err := parseTemplate("header "+name, strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		t.headers = append(t.headers, headerTemplate{name: strings.TrimSpace(name), value: tmpl})
+	}
+
+	body := spec.Body
+	if strings.HasPrefix(body, "@") {
+		content, err := os.ReadFile(body[1:])
+		if err != nil {
+			return nil, fmt.Errorf("reading body file: %w", err)
+		}
+		body = string(content)
+	}
+	if t.body, err = parseTemplate("body", body); err != nil {
+		return nil, err
+	}
+
+	if spec.DataFile != "" {
+		if t.rows, err = readDataFile(spec.DataFile); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Build renders the request for the given sequence number.
+func (t *RequestTemplate) Build(seq int) (*http.Request, error) {
+	return t.build(templateData{Seq: seq})
+}
+
+// Run sends the single request of a job.
+func (t *RequestTemplate) Run(client *http.Client, j Job, results chan<- Result) {
+	startTime := jobStart(j)
+	req, err := t.Build(j.Seq)
+	if err != nil {
+		results <- Result{Timestamp: startTime, Duration: time.Since(startTime), Error: err}
+		return
+	}
+	result, _ := doRequest(client, req, startTime, false, t.assertions)
+	results <- result
+}
+
+func (t *RequestTemplate) build(data templateData) (*http.Request, error) {
+	if len(t.rows) > 0 && data.Row == nil {
+		data.Row = t.rows[data.Seq%len(t.rows)]
+	}
+
+	url, err := render(t.url, data)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if t.body != nil {
+		text, err := render(t.body, data)
+		if err != nil {
+			return nil, err
+		}
+		if text != "" {
+			body = strings.NewReader(text)
+		}
+	}
+
+	req, err := http.NewRequest(t.method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range t.headers {
+		value, err := render(h.value, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add(h.name, value)
+	}
+	return req, nil
+}
+
+func render(tmpl *template.Template, data templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// readDataFile loads a CSV file whose first line names the columns.
+func readDataFile(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening data file: %w", err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading data file: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("data file %s has no rows", path)
+	}
+	columns := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func randInt(min, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + mathrand.Intn(max-min+1)
+}
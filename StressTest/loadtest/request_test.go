@@ -0,0 +1,41 @@
+package loadtest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequestTemplateBuild(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(dataFile, []byte("user,key\nalice,k1\nbob,k2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := NewRequestTemplate(RequestSpec{
+		Method:   "post",
+		URL:      "http://localhost/orders/{{.Seq}}",
+		Headers:  []string{"API_KEY: {{.Row.key}}"},
+		Body:     `{"user":"{{.Row.user}}","amount":{{randInt 5 5}}}`,
+		DataFile: dataFile,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, err := tmpl.Build(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Method != "POST" || req.URL.Path != "/orders/3" {
+		t.Errorf("unexpected request line: %s %s", req.Method, req.URL)
+	}
+	if got := req.Header.Get("API_KEY"); got != "k2" {
+		t.Errorf("expected API_KEY k2, got %q", got)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"user":"bob","amount":5}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
@@ -0,0 +1,252 @@
+// Package loadtest is the engine behind the stress-test CLI: targets (HTTP
+// requests, scenarios, weighted URLs, replays and gRPC calls), closed and
+// open load models, latency histograms, thresholds and reports. A Runner
+// drives a test from Go code, e.g. in an integration test:
+//
+//	target, err := loadtest.NewRequestTemplate(loadtest.RequestSpec{Method: "GET", URL: url})
+//	runner := loadtest.Runner{Target: target, Config: loadtest.LoadConfig{Requests: 1000, Concurrency: 10}}
+//	report, err := runner.Run(ctx)
+//	p95, err := loadtest.ParseThreshold("p95<300ms")
+//	passed := loadtest.ThresholdsPassed(report.Check([]loadtest.Threshold{p95}))
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Result is the outcome of one request.
+type Result struct {
+	Timestamp  time.Time
+	Step       string
+	StatusCode int
+	Proto      string
+	GRPCCode   codes.Code
+	Duration   time.Duration
+	Trace      ConnTrace
+	Error      error
+
+	// Asserted is set when the response was checked against assertions;
+	// AssertionFailures lists the ones it failed
+	Asserted          bool
+	AssertionFailures []string
+}
+
+// Target sends the request(s) of one job and reports a Result for each.
+type Target interface {
+	Run(client *http.Client, j Job, results chan<- Result)
+}
+
+// Runner executes a load test: Config.Concurrency workers run the jobs of
+// Config against Target, and the results are aggregated into a Report.
+type Runner struct {
+	Target Target
+	Config LoadConfig
+
+	// Client sends the HTTP requests; nil uses a client with a 10s timeout
+	Client *http.Client
+
+	// OnResult, when set, is called for every result from a single
+	// goroutine while the test runs
+	OnResult func(Result)
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Config      LoadConfig
+	StartedAt   time.Time
+	Duration    time.Duration
+	Interrupted bool
+	Stats       *Stats
+
+	// Breakdown labels the per-step sections of the printed report, "Step"
+	// when empty
+	Breakdown string
+}
+
+// Run sends the load and returns its report. Canceling ctx stops sending new
+// jobs; the in-flight ones are drained and reported.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	if r.Target == nil {
+		return nil, fmt.Errorf("a target is required")
+	}
+	if err := r.Config.Validate(); err != nil {
+		return nil, err
+	}
+	client := r.Client
+	if client == nil {
+		var err error
+		if client, err = NewHTTPClient(ClientConfig{Timeout: 10 * time.Second}); err != nil {
+			return nil, err
+		}
+	}
+
+	startTime := time.Now()
+	stats := executeStressTest(ctx, client, r.Target, r.Config, r.OnResult)
+	return &Report{
+		Config:      r.Config,
+		StartedAt:   startTime,
+		Duration:    time.Since(startTime),
+		Interrupted: ctx.Err() != nil,
+		Stats:       stats,
+	}, nil
+}
+
+// Stream starts the run in the background and sends every result on the
+// returned channel, which is closed when the run ends. The channel must be
+// drained; wait then returns the report.
+func (r *Runner) Stream(ctx context.Context) (results <-chan Result, wait func() (*Report, error)) {
+	out := make(chan Result, r.Config.Concurrency)
+	var report *Report
+	var err error
+	done := make(chan struct{})
+	runner := *r
+	runner.OnResult = func(result Result) {
+		if r.OnResult != nil {
+			r.OnResult(result)
+		}
+		out <- result
+	}
+	go func() {
+		defer close(done)
+		defer close(out)
+		report, err = runner.Run(ctx)
+	}()
+	return out, func() (*Report, error) {
+		<-done
+		return report, err
+	}
+}
+
+func (r *Report) breakdown() string {
+	if r.Breakdown == "" {
+		return "Step"
+	}
+	return r.Breakdown
+}
+
+// RequestsPerSecond is the throughput over the whole run.
+func (r *Report) RequestsPerSecond() float64 {
+	return r.Stats.RequestsPerSecond(r.Duration)
+}
+
+// Print writes the human-readable report.
+func (r *Report) Print(w io.Writer) {
+	printReport(w, r.Stats, r.Duration, r.breakdown())
+}
+
+// Summary is the machine-readable form of the report.
+func (r *Report) Summary() Summary {
+	return newSummary(r.Config, r.StartedAt, r.Duration, r.Stats)
+}
+
+// Check evaluates the thresholds against the report.
+func (r *Report) Check(thresholds []Threshold) []ThresholdResult {
+	return evaluateThresholds(thresholds, r.Stats, r.Duration)
+}
+
+// WriteHTML renders the report as a self-contained HTML page; series holds
+// the per-second results charted over time.
+func (r *Report) WriteHTML(w io.Writer, title string, series *TimeSeries, thresholds []ThresholdResult) error {
+	return writeHTMLReport(w, title, r.breakdown(), r.StartedAt, r.Duration, r.Stats, series, thresholds, r.Interrupted)
+}
+
+// executeStressTest runs the requests and aggregates their results. When
+// onResult is not nil it is called for every result from a single goroutine.
+// Canceling ctx stops sending new jobs and waits for the in-flight ones.
+func executeStressTest(ctx context.Context, client *http.Client, target Target, cfg LoadConfig, onResult func(Result)) *Stats {
+	stats := NewStats()
+	jobs := make(chan Job, cfg.Concurrency)
+	resultsChan := make(chan Result, cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	// Aggregate results while the workers run
+	done := make(chan struct{})
+	go func() {
+		for result := range resultsChan {
+			stats.Add(result)
+			if onResult != nil {
+				onResult(result)
+			}
+		}
+		close(done)
+	}()
+
+	// Create worker goroutines
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go worker(client, target, jobs, resultsChan, &wg)
+	}
+
+	// Send jobs to workers
+	feedJobs(ctx, cfg, jobs)
+
+	// Wait for all workers to finish
+	wg.Wait()
+	close(resultsChan)
+	<-done
+
+	return stats
+}
+
+func worker(client *http.Client, target Target, jobs <-chan Job, results chan<- Result, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for j := range jobs {
+		target.Run(client, j, results)
+	}
+}
+
+// jobStart is the time a job's latency is measured from: the intended send
+// time for open-model jobs, otherwise now.
+func jobStart(j Job) time.Time {
+	if j.Intended.IsZero() {
+		return time.Now()
+	}
+	return j.Intended
+}
+
+// maxBodySize caps how much of a response is kept for extraction.
+const maxBodySize = 10 << 20
+
+// doRequest sends req, times it from startTime and checks the response
+// against the assertions. The response body is always drained so the
+// connection can be reused, and returned when readBody is set.
+func doRequest(client *http.Client, req *http.Request, startTime time.Time, readBody bool, assertions Assertions) (Result, []byte) {
+	req, tracer := withConnTrace(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Timestamp: startTime, Duration: time.Since(startTime), Trace: tracer.result(), Error: err}, nil
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if readBody || assertions.needsBody() {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	result := Result{
+		Timestamp:  startTime,
+		StatusCode: resp.StatusCode,
+		Proto:      resp.Proto,
+		Duration:   time.Since(startTime),
+		Trace:      tracer.result(),
+		Error:      err,
+	}
+	if err == nil {
+		assertions.apply(&result, response{
+			status:   strconv.Itoa(resp.StatusCode),
+			header:   func(name string) bool { return len(resp.Header.Values(name)) > 0 },
+			body:     body,
+			duration: result.Duration,
+		})
+	}
+	return result, body
+}
@@ -0,0 +1,61 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRunnerRunAndCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	target, err := NewRequestTemplate(RequestSpec{Method: http.MethodGet, URL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runner := Runner{Target: target, Config: LoadConfig{Requests: 20, Concurrency: 4}}
+	report, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Stats.TotalRequests != 20 || report.Stats.SuccessCount != 20 {
+		t.Errorf("expected 20 successful requests, got %d of %d", report.Stats.SuccessCount, report.Stats.TotalRequests)
+	}
+	if summary := report.Summary(); summary.TotalRequests != 20 {
+		t.Errorf("expected 20 requests in the summary, got %d", summary.TotalRequests)
+	}
+
+	threshold, _ := ParseThreshold("error_rate<1%")
+	if !ThresholdsPassed(report.Check([]Threshold{threshold})) {
+		t.Error("expected the error rate threshold to pass")
+	}
+
+	if _, err := (&Runner{Target: target}).Run(context.Background()); err == nil {
+		t.Error("expected an error for a run without load")
+	}
+}
+
+func TestRunnerStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	target, _ := NewRequestTemplate(RequestSpec{Method: http.MethodGet, URL: server.URL})
+	runner := Runner{Target: target, Config: LoadConfig{Requests: 10, Concurrency: 2}}
+	results, wait := runner.Stream(context.Background())
+	n := 0
+	for result := range results {
+		if result.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status %d", result.StatusCode)
+		}
+		n++
+	}
+	report, err := wait()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 10 || report.Stats.TotalRequests != 10 {
+		t.Errorf("expected 10 streamed results, got %d (report %d)", n, report.Stats.TotalRequests)
+	}
+}
@@ -0,0 +1,235 @@
+package loadtest
+
+import (
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioFile is the YAML (or JSON) description of weighted user flows:
+//
+//	data_file: users.csv
+//	scenarios:
+//	  - name: bid
+//	    weight: 3
+//	    steps:
+//	      - name: create auction
+//	        method: POST
+//	        url: http://localhost:8080/auction
+//	        headers: {Content-Type: application/json}
+//	        body: '{"product_name":"book","category":"books","description":"a used book","condition":1}'
+//	      - name: list auctions
+//	        url: http://localhost:8080/auction?status=0
+//	        extract:
+//	          auction_id: $[0].id
+//	      - name: place bid
+//	        method: POST
+//	        url: http://localhost:8080/bid
+//	        body: '{"user_id":"{{uuid}}","auction_id":"{{.Vars.auction_id}}","amount":{{randInt 1 1000}}}'
+//	        assert: [status=201, max-latency=500ms]
+type ScenarioFile struct {
+	DataFile  string         `yaml:"data_file"`
+	Scenarios []ScenarioSpec `yaml:"scenarios"`
+}
+
+type ScenarioSpec struct {
+	Name   string     `yaml:"name"`
+	Weight int        `yaml:"weight"`
+	Steps  []StepSpec `yaml:"steps"`
+}
+
+type StepSpec struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Extract map[string]string `yaml:"extract"`
+	Assert  []string          `yaml:"assert"`
+}
+
+type scenario struct {
+	name   string
+	weight int
+	steps  []step
+}
+
+type step struct {
+	name     string
+	request  *RequestTemplate
+	extract  map[string]string
+	varNames []string
+}
+
+// Scenarios is a Target that runs one weighted flow per job, passing the
+// values extracted from each response on to the following steps. A step
+// that fails one of its assertions also ends the iteration.
+type Scenarios struct {
+	scenarios   []scenario
+	totalWeight int
+	rows        []map[string]string
+}
+
+// LoadScenarios reads a scenario file; assertions apply to every step in
+// addition to the step's own.
+func LoadScenarios(path string, assertions Assertions) (*Scenarios, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario file: %w", err)
+	}
+	var file ScenarioFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("parsing scenario file: %w", err)
+	}
+	return NewScenarios(file, assertions)
+}
+
+func NewScenarios(file ScenarioFile, assertions Assertions) (*Scenarios, error) {
+	if len(file.Scenarios) == 0 {
+		return nil, fmt.Errorf("scenario file has no scenarios")
+	}
+	s := &Scenarios{}
+	if file.DataFile != "" {
+		var err error
+		if s.rows, err = readDataFile(file.DataFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, spec := range file.Scenarios {
+		sc := scenario{name: spec.Name, weight: spec.Weight}
+		if sc.name == "" {
+			sc.name = fmt.Sprintf("scenario %d", i+1)
+		}
+		if sc.weight <= 0 {
+			sc.weight = 1
+		}
+		if len(spec.Steps) == 0 {
+			return nil, fmt.Errorf("scenario %q has no steps", sc.name)
+		}
+		for j, stepSpec := range spec.Steps {
+			st, err := newStep(sc.name, j, stepSpec, assertions)
+			if err != nil {
+				return nil, err
+			}
+			sc.steps = append(sc.steps, st)
+		}
+		s.scenarios = append(s.scenarios, sc)
+		s.totalWeight += sc.weight
+	}
+	return s, nil
+}
+
+func newStep(scenarioName string, index int, spec StepSpec, assertions Assertions) (step, error) {
+	name := spec.Name
+	if name == "" {
+		name = fmt.Sprintf("step %d", index+1)
+	}
+	name = scenarioName + " / " + name
+
+	headers := make([]string, 0, len(spec.Headers))
+	for key, value := range spec.Headers {
+		headers = append(headers, key+": "+value)
+	}
+	sort.Strings(headers)
+
+	stepAssertions := append(Assertions{}, assertions...)
+	for _, expression := range spec.Assert {
+		if err := stepAssertions.Set(expression); err != nil {
+			return step{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	request, err := NewRequestTemplate(RequestSpec{
+		Method:     spec.Method,
+		URL:        spec.URL,
+		Headers:    headers,
+		Body:       spec.Body,
+		Assertions: stepAssertions,
+	})
+	if err != nil {
+		return step{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	varNames := make([]string, 0, len(spec.Extract))
+	for varName, path := range spec.Extract {
+		if _, err := parseJSONPath(path); err != nil {
+			return step{}, fmt.Errorf("%s: %w", name, err)
+		}
+		varNames = append(varNames, varName)
+	}
+	sort.Strings(varNames)
+
+	return step{name: name, request: request, extract: spec.Extract, varNames: varNames}, nil
+}
+
+// StepNames lists every step in file order, for reports.
+func (s *Scenarios) StepNames() []string {
+	var names []string
+	for _, sc := range s.scenarios {
+		for _, st := range sc.steps {
+			names = append(names, st.name)
+		}
+	}
+	return names
+}
+
+func (s *Scenarios) pick() scenario {
+	n := mathrand.Intn(s.totalWeight)
+	for _, sc := range s.scenarios {
+		if n < sc.weight {
+			return sc
+		}
+		n -= sc.weight
+	}
+	return s.scenarios[len(s.scenarios)-1]
+}
+
+// Run executes the steps of one scenario in order. The first step is timed
+// from the job's start so open-model scheduling still applies; a failing step
+// ends the iteration because later steps usually depend on it.
+func (s *Scenarios) Run(client *http.Client, j Job, results chan<- Result) {
+	sc := s.pick()
+	data := templateData{Seq: j.Seq, Vars: make(map[string]string)}
+	if len(s.rows) > 0 {
+		data.Row = s.rows[j.Seq%len(s.rows)]
+	}
+
+	startTime := jobStart(j)
+	for i, st := range sc.steps {
+		if i > 0 {
+			startTime = time.Now()
+		}
+		result := s.runStep(client, st, data, startTime)
+		result.Step = st.name
+		results <- result
+		if result.Error != nil || result.StatusCode >= 400 || len(result.AssertionFailures) > 0 {
+			return
+		}
+	}
+}
+
+func (s *Scenarios) runStep(client *http.Client, st step, data templateData, startTime time.Time) Result {
+	req, err := st.request.build(data)
+	if err != nil {
+		return Result{Timestamp: startTime, Duration: time.Since(startTime), Error: err}
+	}
+	result, body := doRequest(client, req, startTime, len(st.extract) > 0, st.request.assertions)
+	if result.Error != nil {
+		return result
+	}
+	for _, varName := range st.varNames {
+		value, err := jsonPathString(body, st.extract[varName])
+		if err != nil {
+			result.Error = fmt.Errorf("extracting %s: %w", varName, err)
+			return result
+		}
+		data.Vars[varName] = value
+	}
+	return result
+}
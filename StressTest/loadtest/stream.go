@@ -0,0 +1,421 @@
+package loadtest
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Stream kinds of StreamSpec.
+const (
+	StreamWebSocket = "ws"
+	StreamSSE       = "sse"
+)
+
+// Disconnect reasons besides WebSocket close codes and error classes.
+const (
+	disconnectHeld        = "held for duration"
+	disconnectInterrupted = "interrupted"
+	disconnectServer      = "closed by server"
+)
+
+// maxPendingMessages bounds the send times kept per connection while waiting
+// for replies, so a server that does not answer every message cannot grow
+// them forever.
+const maxPendingMessages = 1000
+
+// StreamSpec describes a streaming test: Connections clients each hold a
+// WebSocket or server-sent events connection open for Duration. WebSocket
+// clients may send Message, a template like request bodies, MessageRate
+// times per second.
+type StreamSpec struct {
+	Kind           string
+	URL            string
+	Headers        []string
+	Connections    int
+	Duration       time.Duration
+	Message        string
+	MessageRate    float64
+	ConnectTimeout time.Duration
+	Insecure       bool
+}
+
+// StreamStats aggregates the connections of a streaming test. Round trips
+// pair every received WebSocket message with the oldest unanswered one sent
+// on the same connection, which assumes the server replies once per message
+// as an echo does.
+type StreamStats struct {
+	mu            sync.Mutex
+	Attempted     int
+	Connected     int
+	Failed        int
+	Open          int
+	Sent          int
+	Received      int
+	Connect       *Histogram
+	RoundTrip     *Histogram
+	ConnectErrors map[string]*ErrorClassStats
+	Disconnects   map[string]int
+}
+
+func NewStreamStats() *StreamStats {
+	return &StreamStats{
+		Connect:       NewHistogram(),
+		RoundTrip:     NewHistogram(),
+		ConnectErrors: make(map[string]*ErrorClassStats),
+		Disconnects:   make(map[string]int),
+	}
+}
+
+func (s *StreamStats) attempted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attempted++
+}
+
+func (s *StreamStats) connected(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Connected++
+	s.Open++
+	s.Connect.Record(latency)
+}
+
+// connectFailed records a connection that was never established. A
+// response means the server refused the upgrade or the stream.
+func (s *StreamStats) connectFailed(err error, resp *http.Response) {
+	var class string
+	if resp != nil {
+		class = "status_" + strconv.Itoa(resp.StatusCode)
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+	} else {
+		class = classifyError(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Failed++
+	e, ok := s.ConnectErrors[class]
+	if !ok {
+		e = &ErrorClassStats{}
+		s.ConnectErrors[class] = e
+	}
+	e.add(err)
+}
+
+func (s *StreamStats) disconnected(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Open--
+	s.Disconnects[reason]++
+}
+
+func (s *StreamStats) sent() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Sent++
+}
+
+// received counts a message; sentAt is zero when it answers no message.
+func (s *StreamStats) received(sentAt, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Received++
+	if !sentAt.IsZero() {
+		s.RoundTrip.Record(at.Sub(sentAt))
+	}
+}
+
+// Line renders the live progress line of a streaming test.
+func (s *StreamStats) Line(start time.Time, duration time.Duration) func(time.Time) string {
+	return func(now time.Time) string {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		elapsed := now.Sub(start)
+		line := fmt.Sprintf("open %d/%d | %v | sent %d | received %d", s.Open, s.Attempted, elapsed.Round(time.Second), s.Sent, s.Received)
+		if s.Failed > 0 {
+			line += fmt.Sprintf(" | connect errors %d", s.Failed)
+		}
+		if remaining := duration - elapsed; remaining > 0 {
+			line += fmt.Sprintf(" | ETA %v", remaining.Round(time.Second))
+		}
+		return line
+	}
+}
+
+// RunStreams opens the connections and holds them until the duration is over
+// or ctx is canceled, then closes them and returns.
+func RunStreams(ctx context.Context, spec StreamSpec, client *http.Client, stats *StreamStats) error {
+	header := make(http.Header)
+	for _, h := range spec.Headers {
+		name, value, _ := strings.Cut(h, ":")
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	var message *template.Template
+	if spec.Message != "" {
+		var err error
+		if message, err = parseTemplate("message", spec.Message); err != nil {
+			return err
+		}
+	}
+
+	hold, cancel := context.WithTimeout(ctx, spec.Duration)
+	defer cancel()
+	var seq atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < spec.Connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if spec.Kind == StreamWebSocket {
+				runWebSocket(ctx, hold, spec, header, message, &seq, stats)
+			} else {
+				runSSE(ctx, hold, spec, header, client, stats)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// heldReason is why a connection still open at the end of the hold was
+// closed by the client.
+func heldReason(ctx context.Context) string {
+	if ctx.Err() != nil {
+		return disconnectInterrupted
+	}
+	return disconnectHeld
+}
+
+// disconnectReason names why the server side ended a connection.
+func disconnectReason(err error) string {
+	var closeErr *websocket.CloseError
+	switch {
+	case errors.As(err, &closeErr):
+		return "close " + strconv.Itoa(closeErr.Code)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return disconnectServer
+	}
+	return classifyError(err)
+}
+
+func runWebSocket(ctx, hold context.Context, spec StreamSpec, header http.Header, message *template.Template, seq *atomic.Int64, stats *StreamStats) {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: spec.ConnectTimeout,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: spec.Insecure},
+	}
+	stats.attempted()
+	start := time.Now()
+	conn, resp, err := dialer.DialContext(hold, spec.URL, header)
+	if err != nil {
+		stats.connectFailed(err, resp)
+		return
+	}
+	defer conn.Close()
+	stats.connected(time.Since(start))
+
+	var mu sync.Mutex
+	var pending []time.Time
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				readErr <- err
+				return
+			}
+			now := time.Now()
+			var sentAt time.Time
+			mu.Lock()
+			if len(pending) > 0 {
+				sentAt, pending = pending[0], pending[1:]
+			}
+			mu.Unlock()
+			stats.received(sentAt, now)
+		}
+	}()
+
+	var tick <-chan time.Time
+	if message != nil && spec.MessageRate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / spec.MessageRate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case err := <-readErr:
+			stats.disconnected(disconnectReason(err))
+			return
+		case <-hold.Done():
+			// Close politely and give the server a moment to answer
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			select {
+			case <-readErr:
+			case <-time.After(time.Second):
+			}
+			stats.disconnected(heldReason(ctx))
+			return
+		case <-tick:
+			text, err := render(message, templateData{Seq: int(seq.Add(1)) - 1})
+			if err != nil {
+				stats.disconnected("message template: " + err.Error())
+				return
+			}
+			mu.Lock()
+			if len(pending) >= maxPendingMessages {
+				pending = pending[1:]
+			}
+			pending = append(pending, time.Now())
+			mu.Unlock()
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(text)); err != nil {
+				stats.disconnected(disconnectReason(err))
+				return
+			}
+			stats.sent()
+		}
+	}
+}
+
+// runSSE holds an event stream open and counts its events. The connect
+// latency is the time to the response headers.
+func runSSE(ctx, hold context.Context, spec StreamSpec, header http.Header, client *http.Client, stats *StreamStats) {
+	req, err := http.NewRequestWithContext(hold, http.MethodGet, spec.URL, nil)
+	if err != nil {
+		stats.attempted()
+		stats.connectFailed(err, nil)
+		return
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	stats.attempted()
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		stats.connectFailed(err, nil)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		stats.connectFailed(nil, resp)
+		return
+	}
+	stats.connected(time.Since(start))
+
+	// An event is complete at the blank line following its data
+	reader := bufio.NewReader(resp.Body)
+	data := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if hold.Err() != nil {
+				stats.disconnected(heldReason(ctx))
+			} else {
+				stats.disconnected(disconnectReason(err))
+			}
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && data:
+			stats.received(time.Time{}, time.Now())
+			data = false
+		case strings.HasPrefix(line, "data:") || line == "data":
+			data = true
+		}
+	}
+}
+
+func PrintStreamReport(w io.Writer, stats *StreamStats, totalDuration time.Duration) {
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintln(w, "Stress Test Report")
+	fmt.Fprintln(w, "--------------------------------------------------")
+	fmt.Fprintf(w, "Total time: %v\n", totalDuration)
+	fmt.Fprintf(w, "Connections: %d attempted, %d established, %d failed\n", stats.Attempted, stats.Connected, stats.Failed)
+	if len(stats.ConnectErrors) > 0 {
+		fmt.Fprintln(w, "Connection errors:")
+		classes := make([]string, 0, len(stats.ConnectErrors))
+		for class := range stats.ConnectErrors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			errClass := stats.ConnectErrors[class]
+			fmt.Fprintf(w, "  %s: %d\n", class, errClass.Count)
+			for _, sample := range errClass.Samples {
+				fmt.Fprintf(w, "    e.g. %s\n", sample)
+			}
+		}
+	}
+	printNamedPercentiles(w, "Connect latency", stats.Connect)
+	if stats.Sent > 0 {
+		fmt.Fprintf(w, "Messages sent: %d\n", stats.Sent)
+	}
+	fmt.Fprintf(w, "Messages received: %d\n", stats.Received)
+	if totalDuration > 0 {
+		fmt.Fprintf(w, "Messages received per second: %.2f\n", float64(stats.Received)/totalDuration.Seconds())
+	}
+	printNamedPercentiles(w, "Message round trip", stats.RoundTrip)
+	if len(stats.Disconnects) > 0 {
+		fmt.Fprintln(w, "Disconnect reasons:")
+		reasons := make([]string, 0, len(stats.Disconnects))
+		for reason := range stats.Disconnects {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Fprintf(w, "  %s: %d\n", reason, stats.Disconnects[reason])
+		}
+	}
+	fmt.Fprintln(w, "--------------------------------------------------")
+}
+
+// StreamSummary is the JSON report of a streaming test.
+type StreamSummary struct {
+	URL               string                      `json:"url"`
+	Mode              string                      `json:"mode"`
+	StartedAt         time.Time                   `json:"started_at"`
+	DurationSeconds   float64                     `json:"duration_seconds"`
+	Attempted         int                         `json:"connections_attempted"`
+	Connected         int                         `json:"connections_established"`
+	Failed            int                         `json:"connections_failed"`
+	ConnectErrors     map[string]*ErrorClassStats `json:"connect_errors"`
+	ConnectLatency    LatencySummary              `json:"connect_latency"`
+	Sent              int                         `json:"messages_sent"`
+	Received          int                         `json:"messages_received"`
+	RoundTripLatency  LatencySummary              `json:"round_trip_latency"`
+	DisconnectReasons map[string]int              `json:"disconnect_reasons"`
+	MessageRate       float64                     `json:"message_rate,omitempty"`
+}
+
+func NewStreamSummary(spec StreamSpec, startTime time.Time, totalDuration time.Duration, stats *StreamStats) StreamSummary {
+	return StreamSummary{
+		URL:               spec.URL,
+		Mode:              spec.Kind,
+		StartedAt:         startTime,
+		DurationSeconds:   totalDuration.Seconds(),
+		Attempted:         stats.Attempted,
+		Connected:         stats.Connected,
+		Failed:            stats.Failed,
+		ConnectErrors:     stats.ConnectErrors,
+		ConnectLatency:    newLatencySummary(stats.Connect),
+		Sent:              stats.Sent,
+		Received:          stats.Received,
+		RoundTripLatency:  newLatencySummary(stats.RoundTrip),
+		DisconnectReasons: stats.Disconnects,
+		MessageRate:       spec.MessageRate,
+	}
+}
@@ -0,0 +1,92 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRunStreamsWebSocketEcho(t *testing.T) {
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			kind, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(kind, msg)
+		}
+	}))
+	defer server.Close()
+
+	stats := NewStreamStats()
+	spec := StreamSpec{
+		Kind:        StreamWebSocket,
+		URL:         "ws" + strings.TrimPrefix(server.URL, "http"),
+		Connections: 3,
+		Duration:    300 * time.Millisecond,
+		Message:     `{"seq":{{.Seq}}}`,
+		MessageRate: 20,
+	}
+	if err := RunStreams(context.Background(), spec, nil, stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Connected != 3 || stats.Failed != 0 {
+		t.Errorf("expected 3 connections, got %d (failed %d)", stats.Connected, stats.Failed)
+	}
+	if stats.Sent == 0 || stats.Received != stats.Sent {
+		t.Errorf("expected every sent message echoed, sent %d received %d", stats.Sent, stats.Received)
+	}
+	if stats.RoundTrip.Count() != int64(stats.Received) {
+		t.Errorf("expected a round trip per echo, got %d", stats.RoundTrip.Count())
+	}
+	if stats.Disconnects[disconnectHeld] != 3 {
+		t.Errorf("expected 3 connections held for the duration, got %v", stats.Disconnects)
+	}
+}
+
+func TestRunStreamsSSE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 2; i++ {
+			fmt.Fprintf(w, "event: tick\ndata: %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+		// The server ends the stream before the duration
+	}))
+	defer server.Close()
+
+	stats := NewStreamStats()
+	spec := StreamSpec{Kind: StreamSSE, URL: server.URL, Headers: []string{"X-Token: secret"}, Connections: 2, Duration: time.Second}
+	if err := RunStreams(context.Background(), spec, http.DefaultClient, stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Connected != 2 || stats.Received != 4 {
+		t.Errorf("expected 2 streams with 4 events, got %d streams and %d events", stats.Connected, stats.Received)
+	}
+	if stats.Disconnects[disconnectServer] != 2 {
+		t.Errorf("expected 2 streams closed by the server, got %v", stats.Disconnects)
+	}
+
+	stats = NewStreamStats()
+	spec.Headers = nil
+	RunStreams(context.Background(), spec, http.DefaultClient, stats)
+	if stats.Failed != 2 || stats.ConnectErrors["status_401"] == nil {
+		t.Errorf("expected 2 connections refused with 401, got %v", stats.ConnectErrors)
+	}
+}
@@ -0,0 +1,227 @@
+package loadtest
+
+import (
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ways of choosing the target of each request.
+const (
+	PickRandom     = "random"
+	PickRoundRobin = "round-robin"
+)
+
+// TargetsFile is the YAML (or JSON) list of weighted requests:
+//
+//	targets:
+//	  - name: list auctions
+//	    url: http://localhost:8080/auction?status=0
+//	    weight: 8
+//	  - name: place bid
+//	    method: POST
+//	    url: http://localhost:8080/bid
+//	    headers: {Content-Type: application/json}
+//	    body: '{"user_id":"{{uuid}}","auction_id":"1","amount":{{randInt 1 1000}}}'
+//	    weight: 2
+//	    assert: [status=201]
+//
+// Any other file lists one target per line as "[weight] [METHOD] URL",
+// with # starting a comment.
+type TargetsFile struct {
+	Targets []TargetSpec `yaml:"targets"`
+}
+
+type TargetSpec struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Weight defaults to 1 when unset
+	Weight *int     `yaml:"weight"`
+	Assert []string `yaml:"assert"`
+}
+
+type weightedRequest struct {
+	name    string
+	weight  int
+	request *RequestTemplate
+}
+
+// MultiTarget spreads the requests over weighted targets, chosen at random
+// or in a weighted round-robin order. Results carry the target name as
+// their step, so the report breaks the stats down per target.
+type MultiTarget struct {
+	targets     []weightedRequest
+	totalWeight int
+	order       []int // round-robin order, nil when picking at random
+}
+
+// LoadTargets reads a targets file. The global headers, data file and
+// assertions apply to every target in addition to its own.
+func LoadTargets(file, pick string, spec RequestSpec) (*MultiTarget, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading targets file: %w", err)
+	}
+	var targets TargetsFile
+	switch strings.ToLower(path.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		err = yaml.Unmarshal(content, &targets)
+	default:
+		targets.Targets, err = parseTargetList(string(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing targets file: %w", err)
+	}
+	return NewMultiTarget(targets, pick, spec)
+}
+
+// parseTargetList reads "[weight] [METHOD] URL" lines.
+func parseTargetList(content string) ([]TargetSpec, error) {
+	var specs []TargetSpec
+	for i, line := range strings.Split(content, "\n") {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var spec TargetSpec
+		if weight, err := strconv.Atoi(fields[0]); err == nil {
+			spec.Weight = &weight
+			fields = fields[1:]
+		}
+		if len(fields) == 2 {
+			spec.Method = fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("line %d: expected \"[weight] [METHOD] URL\"", i+1)
+		}
+		spec.URL = fields[0]
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func NewMultiTarget(file TargetsFile, pick string, global RequestSpec) (*MultiTarget, error) {
+	if len(file.Targets) == 0 {
+		return nil, fmt.Errorf("targets file has no targets")
+	}
+	t := &MultiTarget{}
+	for i, spec := range file.Targets {
+		weight := 1
+		if spec.Weight != nil {
+			if *spec.Weight < 1 {
+				return nil, fmt.Errorf("target %d: weight must be at least 1", i+1)
+			}
+			weight = *spec.Weight
+		}
+		method := spec.Method
+		if method == "" {
+			method = global.Method
+		}
+		name := spec.Name
+		if name == "" {
+			name = strings.ToUpper(method) + " " + spec.URL
+		}
+
+		headers := append([]string{}, global.Headers...)
+		keys := make([]string, 0, len(spec.Headers))
+		for key := range spec.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			headers = append(headers, key+": "+spec.Headers[key])
+		}
+
+		assertions := append(Assertions{}, global.Assertions...)
+		for _, expression := range spec.Assert {
+			if err := assertions.Set(expression); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		request, err := NewRequestTemplate(RequestSpec{
+			Method:     method,
+			URL:        spec.URL,
+			Headers:    headers,
+			Body:       spec.Body,
+			DataFile:   global.DataFile,
+			Assertions: assertions,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		t.targets = append(t.targets, weightedRequest{name: name, weight: weight, request: request})
+		t.totalWeight += weight
+	}
+
+	switch pick {
+	case PickRandom:
+	case PickRoundRobin:
+		t.order = roundRobinOrder(t.targets, t.totalWeight)
+	default:
+		return nil, fmt.Errorf("invalid --pick %q, expected %s or %s", pick, PickRandom, PickRoundRobin)
+	}
+	return t, nil
+}
+
+// roundRobinOrder interleaves the targets in proportion to their weights
+// (smooth weighted round-robin), so heavy targets are not sent in bursts.
+func roundRobinOrder(targets []weightedRequest, totalWeight int) []int {
+	order := make([]int, totalWeight)
+	current := make([]int, len(targets))
+	for i := range order {
+		best := 0
+		for j, target := range targets {
+			current[j] += target.weight
+			if current[j] > current[best] {
+				best = j
+			}
+		}
+		current[best] -= totalWeight
+		order[i] = best
+	}
+	return order
+}
+
+func (t *MultiTarget) pick(seq int) weightedRequest {
+	if t.order != nil {
+		return t.targets[t.order[seq%len(t.order)]]
+	}
+	n := mathrand.Intn(t.totalWeight)
+	for _, target := range t.targets {
+		if n < target.weight {
+			return target
+		}
+		n -= target.weight
+	}
+	return t.targets[len(t.targets)-1]
+}
+
+// Run sends the request of the target picked for the job.
+func (t *MultiTarget) Run(client *http.Client, j Job, results chan<- Result) {
+	target := t.pick(j.Seq)
+	startTime := jobStart(j)
+	req, err := target.request.Build(j.Seq)
+	if err != nil {
+		results <- Result{Timestamp: startTime, Step: target.name, Duration: time.Since(startTime), Error: err}
+		return
+	}
+	result, _ := doRequest(client, req, startTime, false, target.request.assertions)
+	result.Step = target.name
+	results <- result
+}
@@ -0,0 +1,80 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTargetList(t *testing.T) {
+	specs, err := parseTargetList("# mixed workload\n3 http://svc/read\n\n1 POST http://svc/write # writes\nhttp://svc/health\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []TargetSpec{
+		{URL: "http://svc/read", Weight: weight(3)},
+		{URL: "http://svc/write", Method: "POST", Weight: weight(1)},
+		{URL: "http://svc/health"},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("expected %+v, got %+v", want, specs)
+	}
+	if _, err := parseTargetList("2 GET http://a http://b"); err == nil {
+		t.Error("expected an error for a line with two URLs")
+	}
+}
+
+func weight(n int) *int {
+	return &n
+}
+
+func TestNewMultiTargetRejectsZeroWeight(t *testing.T) {
+	for _, w := range []int{0, -1} {
+		_, err := NewMultiTarget(TargetsFile{Targets: []TargetSpec{
+			{URL: "http://svc/read"},
+			{URL: "http://svc/write", Weight: weight(w)},
+		}}, PickRandom, RequestSpec{Method: http.MethodGet})
+		if err == nil || !strings.Contains(err.Error(), "target 2") {
+			t.Errorf("weight %d: expected an error for target 2, got %v", w, err)
+		}
+	}
+}
+
+func TestRoundRobinOrderInterleavesByWeight(t *testing.T) {
+	targets := []weightedRequest{{name: "a", weight: 3}, {name: "b", weight: 1}}
+	order := roundRobinOrder(targets, 4)
+	if want := []int{0, 0, 1, 0}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
+
+func TestMultiTargetReportsPerTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	target, err := NewMultiTarget(TargetsFile{Targets: []TargetSpec{
+		{Name: "read", URL: server.URL + "/items", Weight: weight(3)},
+		{Name: "write", Method: "POST", URL: server.URL + "/items", Assert: []string{"status=201"}},
+	}}, PickRoundRobin, RequestSpec{Method: http.MethodGet})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := executeStressTest(context.Background(), server.Client(), target, LoadConfig{Requests: 8, Concurrency: 2}, nil)
+	if stats.TotalRequests != 8 {
+		t.Fatalf("expected 8 requests, got %d", stats.TotalRequests)
+	}
+	if got := stats.Steps["read"].StatusCodes[200]; got != 6 {
+		t.Errorf("expected 6 reads, got %d", got)
+	}
+	if got := stats.Steps["write"].SuccessCount; got != 2 {
+		t.Errorf("expected 2 successful writes, got %d", got)
+	}
+}
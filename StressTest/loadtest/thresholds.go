@@ -0,0 +1,219 @@
+package loadtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Threshold is a pass/fail condition evaluated on the final stats, written
+// as metric, operator and value:
+//
+//	p95<300ms        latency percentile (also min, mean and max) of the
+//	                 requests that got a response; fails when none did
+//	error_rate<1%    requests without a response, with a 5xx status or,
+//	                 for gRPC, with a status other than OK
+//	status_429<5%    share of a status code, or of a class such as status_4xx
+//	assertion_failure_rate<1%
+//	                 responses that failed an assertion
+//	rps>=100         achieved requests per second
+type Threshold struct {
+	Expression string
+	metric     string
+	op         string
+	value      float64
+}
+
+// ThresholdResult is the outcome of one threshold.
+type ThresholdResult struct {
+	Expression string `json:"expression"`
+	Actual     string `json:"actual"`
+	Passed     bool   `json:"passed"`
+}
+
+// Thresholds is a set of pass/fail conditions; it collects repeatable
+// --threshold flags.
+type Thresholds []Threshold
+
+func (t *Thresholds) String() string {
+	expressions := make([]string, len(*t))
+	for i, threshold := range *t {
+		expressions[i] = threshold.Expression
+	}
+	return strings.Join(expressions, ", ")
+}
+
+func (t *Thresholds) Set(value string) error {
+	threshold, err := ParseThreshold(value)
+	if err != nil {
+		return err
+	}
+	*t = append(*t, threshold)
+	return nil
+}
+
+var thresholdOperators = []string{"<=", ">=", "<", ">"}
+
+func ParseThreshold(expression string) (Threshold, error) {
+	text := strings.ReplaceAll(expression, " ", "")
+	for _, op := range thresholdOperators {
+		metric, valueText, ok := strings.Cut(text, op)
+		if !ok {
+			continue
+		}
+		threshold := Threshold{Expression: expression, metric: metric, op: op}
+		var err error
+		switch {
+		case isLatencyMetric(metric):
+			var d time.Duration
+			d, err = time.ParseDuration(valueText)
+			threshold.value = float64(d)
+		case metric == "error_rate" || metric == "assertion_failure_rate" || isStatusMetric(metric):
+			threshold.value, err = ParseRatio(valueText)
+		case metric == "rps":
+			threshold.value, err = strconv.ParseFloat(valueText, 64)
+		default:
+			return Threshold{}, fmt.Errorf("unknown threshold metric %q", metric)
+		}
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold value in %q: %w", expression, err)
+		}
+		return threshold, nil
+	}
+	return Threshold{}, fmt.Errorf("invalid threshold %q, expected e.g. p95<300ms", expression)
+}
+
+func isLatencyMetric(metric string) bool {
+	switch metric {
+	case "min", "mean", "max":
+		return true
+	}
+	if !strings.HasPrefix(metric, "p") {
+		return false
+	}
+	p, err := strconv.ParseFloat(metric[1:], 64)
+	return err == nil && p > 0 && p <= 100
+}
+
+// isStatusMetric matches status_429 or a status class such as status_5xx.
+func isStatusMetric(metric string) bool {
+	code, ok := strings.CutPrefix(metric, "status_")
+	if !ok || len(code) != 3 || code[0] < '1' || code[0] > '5' {
+		return false
+	}
+	if code[1:] == "xx" {
+		return true
+	}
+	_, err := strconv.Atoi(code)
+	return err == nil
+}
+
+// ParseRatio accepts either a percentage ("5%") or a fraction ("0.05").
+func ParseRatio(text string) (float64, error) {
+	if strings.HasSuffix(text, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64)
+		return v / 100, err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// Evaluate checks the threshold against the stats of a finished run.
+func (t Threshold) Evaluate(stats *Stats, totalDuration time.Duration) ThresholdResult {
+	var actual float64
+	var actualText string
+	switch {
+	case isLatencyMetric(t.metric):
+		// Failed requests record no latency, so a run where every request
+		// failed has nothing to compare; it must not pass as fast
+		if stats.Latency.Count() == 0 {
+			return ThresholdResult{Expression: t.Expression, Actual: "no data", Passed: false}
+		}
+		var d time.Duration
+		switch t.metric {
+		case "min":
+			d = stats.Latency.Min()
+		case "mean":
+			d = stats.Latency.Mean()
+		case "max":
+			d = stats.Latency.Max()
+		default:
+			p, _ := strconv.ParseFloat(t.metric[1:], 64)
+			d = stats.Latency.Percentile(p)
+		}
+		actual, actualText = float64(d), round(d).String()
+	case t.metric == "rps":
+		actual = stats.RequestsPerSecond(totalDuration)
+		actualText = strconv.FormatFloat(actual, 'f', 2, 64)
+	default:
+		actual = stats.ratio(t.metric)
+		actualText = strconv.FormatFloat(actual*100, 'f', 2, 64) + "%"
+	}
+
+	var passed bool
+	switch t.op {
+	case "<":
+		passed = actual < t.value
+	case "<=":
+		passed = actual <= t.value
+	case ">":
+		passed = actual > t.value
+	case ">=":
+		passed = actual >= t.value
+	}
+	return ThresholdResult{Expression: t.Expression, Actual: actualText, Passed: passed}
+}
+
+// ratio returns the share of requests matching a ratio metric.
+func (s *Stats) ratio(metric string) float64 {
+	if s.TotalRequests == 0 {
+		return 0
+	}
+	var matched int
+	switch {
+	case metric == "error_rate":
+		matched = s.FailedCount
+		for code, count := range s.StatusCodes {
+			if code >= 500 {
+				matched += count
+			}
+		}
+		for name, count := range s.GRPCCodes {
+			if name != codes.OK.String() {
+				matched += count
+			}
+		}
+	case metric == "assertion_failure_rate":
+		matched = s.AssertionFailed
+	case strings.HasSuffix(metric, "xx"):
+		class := strings.TrimSuffix(strings.TrimPrefix(metric, "status_"), "xx")
+		for code, count := range s.StatusCodes {
+			if strconv.Itoa(code/100) == class {
+				matched += count
+			}
+		}
+	default:
+		code, _ := strconv.Atoi(strings.TrimPrefix(metric, "status_"))
+		matched = s.StatusCodes[code]
+	}
+	return float64(matched) / float64(s.TotalRequests)
+}
+
+func evaluateThresholds(thresholds []Threshold, stats *Stats, totalDuration time.Duration) []ThresholdResult {
+	results := make([]ThresholdResult, 0, len(thresholds))
+	for _, t := range thresholds {
+		results = append(results, t.Evaluate(stats, totalDuration))
+	}
+	return results
+}
+
+func ThresholdsPassed(results []ThresholdResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThresholdEvaluate(t *testing.T) {
+	stats := NewStats()
+	for i := 0; i < 90; i++ {
+		stats.Add(Result{StatusCode: 200, Duration: 100 * time.Millisecond})
+	}
+	for i := 0; i < 8; i++ {
+		stats.Add(Result{StatusCode: 429, Duration: 10 * time.Millisecond})
+	}
+	for i := 0; i < 2; i++ {
+		stats.Add(Result{StatusCode: 503, Duration: 500 * time.Millisecond})
+	}
+
+	cases := map[string]bool{
+		"p95<300ms":      true,
+		"p99 < 300ms":    false,
+		"max<500ms":      false,
+		"error_rate<1%":  false,
+		"error_rate<3%":  true,
+		"status_429<5%":  false,
+		"status_4xx<0.1": true,
+		"rps>=10":        true,
+	}
+	for expression, want := range cases {
+		threshold, err := ParseThreshold(expression)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", expression, err)
+		}
+		if got := threshold.Evaluate(stats, 10*time.Second); got.Passed != want {
+			t.Errorf("%s: expected passed=%v, got %v (actual %s)", expression, want, got.Passed, got.Actual)
+		}
+	}
+
+	for _, expression := range []string{"p95", "latency<1s", "status_abc<1%", "p95<fast"} {
+		if _, err := ParseThreshold(expression); err == nil {
+			t.Errorf("%s: expected an error", expression)
+		}
+	}
+}
+
+func TestLatencyThresholdFailsWithoutResponses(t *testing.T) {
+	stats := NewStats()
+	for i := 0; i < 10; i++ {
+		stats.Add(Result{Error: context.DeadlineExceeded, Duration: time.Second})
+	}
+
+	for _, expression := range []string{"p95<200ms", "max<1s", "mean>=0s"} {
+		threshold, _ := ParseThreshold(expression)
+		if got := threshold.Evaluate(stats, time.Second); got.Passed || got.Actual != "no data" {
+			t.Errorf("%s: expected to fail with no data, got %+v", expression, got)
+		}
+	}
+}
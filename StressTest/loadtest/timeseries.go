@@ -0,0 +1,134 @@
+package loadtest
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// seriesOpenSeconds is how many of the latest seconds keep a full histogram,
+// so results that complete slightly out of order still land in their second.
+const seriesOpenSeconds = 3
+
+// TimePoint holds the results that completed during one second of the run.
+type TimePoint struct {
+	Second   int            `json:"second"`
+	Requests int            `json:"requests"`
+	Errors   int            `json:"errors"`
+	Statuses map[string]int `json:"statuses"`
+	P50      time.Duration  `json:"p50"`
+	P95      time.Duration  `json:"p95"`
+	P99      time.Duration  `json:"p99"`
+}
+
+// TimeSeries aggregates results per second of the run. Only the latest
+// seconds keep a histogram; older ones are reduced to a few percentiles.
+type TimeSeries struct {
+	mu     sync.Mutex
+	start  time.Time
+	points []TimePoint
+	open   map[int]*Histogram
+}
+
+func NewTimeSeries(start time.Time) *TimeSeries {
+	return &TimeSeries{start: start, open: make(map[int]*Histogram)}
+}
+
+// Add records a result at the time it completed.
+func (ts *TimeSeries) Add(result Result) {
+	label := strconv.Itoa(result.StatusCode)
+	switch {
+	case result.Error != nil:
+		label = classifyError(result.Error)
+	case result.Proto == "grpc":
+		label = result.GRPCCode.String()
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	point, h := ts.at(result.Timestamp.Add(result.Duration))
+	point.Requests++
+	point.Statuses[label]++
+	if errorClass(result) != "" {
+		point.Errors++
+	}
+	if result.Error == nil && h != nil {
+		h.Record(result.Duration)
+	}
+}
+
+// AddStats records a batch of aggregated results received at a given time,
+// as streamed by distributed agents.
+func (ts *TimeSeries) AddStats(at time.Time, delta *Stats) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	point, h := ts.at(at)
+	point.Requests += delta.TotalRequests
+	point.Errors += int(delta.ratio("error_rate")*float64(delta.TotalRequests) + 0.5)
+	for code, count := range delta.StatusCodes {
+		point.Statuses[strconv.Itoa(code)] += count
+	}
+	for name, count := range delta.GRPCCodes {
+		point.Statuses[name] += count
+	}
+	for class, e := range delta.ErrorClasses {
+		point.Statuses[class] += e.Count
+	}
+	if h != nil {
+		h.Merge(delta.Latency)
+	}
+}
+
+// at returns the point of the second t falls in, and its histogram unless
+// the second is already closed.
+func (ts *TimeSeries) at(t time.Time) (*TimePoint, *Histogram) {
+	second := int(t.Sub(ts.start) / time.Second)
+	if second < 0 {
+		second = 0
+	}
+	for len(ts.points) <= second {
+		s := len(ts.points)
+		ts.points = append(ts.points, TimePoint{Second: s, Statuses: make(map[string]int)})
+		ts.open[s] = NewHistogram()
+	}
+	for s, h := range ts.open {
+		if s <= second-seriesOpenSeconds {
+			ts.close(s, h)
+		}
+	}
+	return &ts.points[second], ts.open[second]
+}
+
+func (ts *TimeSeries) close(second int, h *Histogram) {
+	point := &ts.points[second]
+	point.P50, point.P95, point.P99 = h.Percentile(50), h.Percentile(95), h.Percentile(99)
+	delete(ts.open, second)
+}
+
+// Points closes the remaining seconds and returns the series in order.
+func (ts *TimeSeries) Points() []TimePoint {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for s, h := range ts.open {
+		ts.close(s, h)
+	}
+	return ts.points
+}
+
+// Statuses returns every status label seen in the series, sorted.
+func (ts *TimeSeries) Statuses() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	seen := make(map[string]bool)
+	for _, point := range ts.points {
+		for label := range point.Statuses {
+			seen[label] = true
+		}
+	}
+	labels := make([]string, 0, len(seen))
+	for label := range seen {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
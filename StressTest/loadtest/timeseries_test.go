@@ -0,0 +1,34 @@
+package loadtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeSeriesGroupsResultsBySecond(t *testing.T) {
+	start := time.Now()
+	ts := NewTimeSeries(start)
+	ts.Add(Result{Timestamp: start, StatusCode: 200, Duration: 100 * time.Millisecond})
+	ts.Add(Result{Timestamp: start.Add(900 * time.Millisecond), StatusCode: 500, Duration: 200 * time.Millisecond})
+	ts.Add(Result{Timestamp: start.Add(5 * time.Second), Error: errors.New("boom"), Duration: time.Millisecond})
+	// Completes in second 1, after second 5 was opened
+	ts.Add(Result{Timestamp: start.Add(time.Second), StatusCode: 200, Duration: 300 * time.Millisecond})
+
+	points := ts.Points()
+	if len(points) != 6 {
+		t.Fatalf("expected 6 seconds, got %d", len(points))
+	}
+	if points[0].Requests != 1 || points[0].P50 != 100*time.Millisecond {
+		t.Errorf("second 0: unexpected %+v", points[0])
+	}
+	if points[1].Requests != 2 || points[1].Errors != 1 || points[1].Statuses["500"] != 1 || points[1].Statuses["200"] != 1 {
+		t.Errorf("second 1: unexpected %+v", points[1])
+	}
+	if points[5].Statuses[ErrClassOther] != 1 || points[5].Errors != 1 {
+		t.Errorf("second 5: unexpected %+v", points[5])
+	}
+	if got := ts.Statuses(); len(got) != 3 {
+		t.Errorf("expected 3 status labels, got %v", got)
+	}
+}
@@ -1,133 +1,278 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"net/http"
+	"io"
 	"os"
-	"sync"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
 )
 
-type Result struct {
-	StatusCode int
-	Duration   time.Duration
-	Error      error
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
+		case "coordinator":
+			os.Exit(runCoordinator(os.Args[2:]))
+		case "compare":
+			os.Exit(runCompare(os.Args[2:]))
+		case "capacity":
+			os.Exit(runCapacity(os.Args[2:]))
+		}
+	}
+	os.Exit(run(os.Args[1:]))
 }
 
-func main() {
+// run executes a local stress test and returns the process exit code.
+func run(args []string) int {
 	// Parse command line arguments
-	url := flag.String("url", "", "URL of the service to be tested")
-	requests := flag.Int("requests", 0, "Number of total requests")
-	concurrency := flag.Int("concurrency", 0, "Number of concurrent calls")
-	flag.Parse()
+	var opts Options
+	if err := newFlagSet("stress-test", &opts).Parse(args); err != nil {
+		return exitUsage(err)
+	}
 
 	// Validate input parameters
-	if *url == "" || *requests <= 0 || *concurrency <= 0 {
+	cfg, err := opts.LoadConfig()
+	if err != nil {
 		fmt.Println("All parameters are required and must be valid")
-		fmt.Println("Usage: --url=<url> --requests=<requests> --concurrency=<concurrency>")
-		os.Exit(1)
+		fmt.Println(usage)
+		fmt.Println(err)
+		return 1
+	}
+	if opts.Streaming() {
+		return runStream(&opts, opts.StreamSpec(cfg))
+	}
+
+	target, description, err := opts.NewTarget(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer closeTarget(target)
+	client, err := opts.NewClient()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	sink, err := newReportSink(&opts)
+	if err != nil {
+		fmt.Println(err)
+		return 1
 	}
+	defer sink.Close()
 
 	// Execute the stress test
-	fmt.Printf("Starting stress test for %s\n", *url)
-	fmt.Printf("Total requests: %d\n", *requests)
-	fmt.Printf("Concurrency level: %d\n", *concurrency)
-	fmt.Println("--------------------------------------------------")
+	opts.printHeader(sink.console, description, cfg)
+
+	ctx, stop := interruptContext()
+	defer stop()
 
 	startTime := time.Now()
-	results := executeStressTest(*url, *requests, *concurrency)
-	totalDuration := time.Since(startTime)
+	sink.Start(startTime)
+	progress := loadtest.NewProgress(cfg, startTime)
+	runner := loadtest.Runner{
+		Target: target,
+		Config: cfg,
+		Client: client,
+		OnResult: func(result loadtest.Result) {
+			progress.Add(result)
+			sink.WriteResult(result)
+		},
+	}
+	stopProgress := startProgress(progress.Line, opts.Progress)
+	report, err := runner.Run(ctx)
+	stopProgress()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
 
 	// Generate and print report
-	printReport(results, totalDuration, *requests)
+	return sink.Finish(report)
 }
 
-func executeStressTest(url string, totalRequests, concurrency int) []Result {
-	results := make([]Result, 0, totalRequests)
-	jobs := make(chan int, totalRequests)
-	resultsChan := make(chan Result, totalRequests)
-	var wg sync.WaitGroup
-
-	// Create worker goroutines
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go worker(url, jobs, resultsChan, &wg)
+// exitUsage reports a flag parsing error; asking for help is not a failure.
+func exitUsage(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
 	}
+	return 1
+}
 
-	// Send jobs to workers
-	for i := 0; i < totalRequests; i++ {
-		jobs <- i
+// interruptContext is canceled by Ctrl-C or SIGTERM so the run stops sending
+// new requests while in-flight ones are drained and reported. A second
+// Ctrl-C exits immediately.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// startProgress prints the live progress line until the returned function
+// is called.
+func startProgress(line func(time.Time) string, interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runProgress(ctx, os.Stderr, interval, line)
+	}()
+	return func() {
+		cancel()
+		<-done
 	}
-	close(jobs)
+}
+
+// reportSink writes the human-readable report and, optionally, the
+// machine-readable and HTML ones.
+type reportSink struct {
+	opts    *Options
+	console io.Writer
+	output  loadtest.OutputWriter
+	file    *os.File
+	series  *loadtest.TimeSeries
+	export  *loadtest.MetricsExporter
+}
 
-	// Wait for all workers to finish
-	wg.Wait()
-	close(resultsChan)
+func newReportSink(opts *Options) (*reportSink, error) {
+	// The human-readable report moves to stderr when stdout carries the
+	// machine-readable one
+	sink := &reportSink{opts: opts, console: os.Stdout}
+	if opts.Output == "" {
+		return sink, nil
+	}
+	var w io.Writer = os.Stdout
+	if opts.OutputFile != "" {
+		f, err := os.Create(opts.OutputFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating output file: %w", err)
+		}
+		sink.file = f
+		w = f
+	} else {
+		sink.console = os.Stderr
+	}
+	output, err := loadtest.NewOutputWriter(opts.Output, w)
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	sink.output = output
+	return sink, nil
+}
 
-	// Collect results
-	for result := range resultsChan {
-		results = append(results, result)
+// Start marks the beginning of the run; the HTML report charts results per
+// second from here, and live metrics are pushed from here on.
+func (r *reportSink) Start(startTime time.Time) {
+	if r.opts.HTML != "" {
+		r.series = loadtest.NewTimeSeries(startTime)
 	}
+	if r.opts.Pushgateway != "" || r.opts.OTLP != "" {
+		r.export = loadtest.NewMetricsExporter(r.opts.Pushgateway, r.opts.OTLP, r.opts.targetName(), startTime)
+		r.export.Start(r.opts.ExportInterval)
+	}
+}
 
-	return results
+func (r *reportSink) WriteResult(result loadtest.Result) {
+	if r.series != nil {
+		r.series.Add(result)
+	}
+	if r.export != nil {
+		r.export.Add(result)
+	}
+	if r.output == nil {
+		return
+	}
+	if err := r.output.WriteResult(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing result: %v\n", err)
+	}
 }
 
-func worker(url string, jobs <-chan int, results chan<- Result, wg *sync.WaitGroup) {
-	defer wg.Done()
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// AddStats records a batch of results aggregated by distributed agents.
+func (r *reportSink) AddStats(at time.Time, delta *loadtest.Stats) {
+	if r.series != nil {
+		r.series.AddStats(at, delta)
+	}
+	if r.export != nil {
+		r.export.AddStats(delta)
 	}
+}
 
-	for range jobs {
-		startTime := time.Now()
-		resp, err := client.Get(url)
-		duration := time.Since(startTime)
+// Finish prints the report, evaluates the thresholds and returns the exit
+// code of the run.
+func (r *reportSink) Finish(report *loadtest.Report) int {
+	if report.Interrupted {
+		fmt.Fprintln(r.console, "Interrupted: stopped sending requests and drained the ones in flight")
+	}
+	report.Breakdown = r.opts.breakdown()
+	report.Print(r.console)
+	if r.export != nil {
+		r.export.Finish(r.console)
+		r.export = nil
+	}
+	thresholdResults := report.Check(r.opts.Thresholds)
+	loadtest.PrintThresholds(r.console, thresholdResults)
 
-		result := Result{
-			Duration: duration,
-			Error:    err,
+	if r.output != nil {
+		summary := report.Summary()
+		summary.URL = r.opts.targetName()
+		summary.Scenario = r.opts.Scenario
+		summary.Thresholds = thresholdResults
+		if err := r.output.Close(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s report: %v\n", r.opts.Output, err)
+			return 1
 		}
+	}
 
-		if err == nil {
-			result.StatusCode = resp.StatusCode
-			resp.Body.Close()
+	if r.series != nil {
+		if err := r.writeHTML(report, thresholdResults); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing HTML report: %v\n", err)
+			return 1
 		}
+	}
 
-		results <- result
+	if !loadtest.ThresholdsPassed(thresholdResults) {
+		fmt.Fprintln(r.console, "Thresholds failed")
+		return exitThresholdsFailed
 	}
+	return 0
 }
 
-func printReport(results []Result, totalDuration time.Duration, totalRequests int) {
-	// Count status codes
-	statusCodes := make(map[int]int)
-	var successCount int
-	var failedCount int
-
-	for _, result := range results {
-		if result.Error != nil {
-			failedCount++
-		} else {
-			statusCodes[result.StatusCode]++
-			if result.StatusCode == 200 {
-				successCount++
-			}
-		}
+func (r *reportSink) writeHTML(report *loadtest.Report, thresholds []loadtest.ThresholdResult) error {
+	f, err := os.Create(r.opts.HTML)
+	if err != nil {
+		return err
+	}
+	err = report.WriteHTML(f, r.opts.description(), r.series, thresholds)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
 	}
+	return err
+}
 
-	// Print report
-	fmt.Println("--------------------------------------------------")
-	fmt.Println("Stress Test Report")
-	fmt.Println("--------------------------------------------------")
-	fmt.Printf("Total time: %v\n", totalDuration)
-	fmt.Printf("Total requests: %d\n", totalRequests)
-	fmt.Printf("Successful requests (HTTP 200): %d\n", successCount)
-	fmt.Println("Status code distribution:")
-	for code, count := range statusCodes {
-		fmt.Printf("  HTTP %d: %d\n", code, count)
+func (r *reportSink) Close() {
+	if r.export != nil {
+		r.export.Finish(r.console)
 	}
-	if failedCount > 0 {
-		fmt.Printf("Failed requests: %d\n", failedCount)
+	if r.file != nil {
+		r.file.Close()
 	}
-	fmt.Println("--------------------------------------------------")
 }
+
+// exitThresholdsFailed is the exit code of a run that broke a threshold,
+// distinct from the usage error code 1.
+const exitThresholdsFailed = 2
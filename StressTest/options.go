@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
+)
+
+// Options holds the command line flags of a test run. Agents parse the
+// same flags, so a distributed plan is just a list of arguments.
+type Options struct {
+	URL         string
+	Requests    int
+	Concurrency int
+	Duration    time.Duration
+	Rate        float64
+	Stages      string
+
+	Method   string
+	Headers  headerFlags
+	Body     string
+	DataFile string
+	Scenario string
+	Targets  string
+	Pick     string
+
+	Replay      string
+	ReplaySpeed float64
+	replay      []loadtest.ReplayEntry
+
+	GRPCTarget  string
+	GRPCMethod  string
+	ProtoFiles  stringsFlag
+	ImportPaths stringsFlag
+	GRPCTLS     bool
+
+	WS          string
+	SSE         string
+	Message     string
+	MessageRate float64
+
+	Timeout          time.Duration
+	ConnectTimeout   time.Duration
+	TLSTimeout       time.Duration
+	Protocol         string
+	DisableKeepAlive bool
+	MaxConnsPerHost  int
+	Insecure         bool
+	CertFile         string
+	KeyFile          string
+
+	Assertions loadtest.Assertions
+	Progress   time.Duration
+	Thresholds loadtest.Thresholds
+	Output     string
+	OutputFile string
+	HTML       string
+
+	Pushgateway    string
+	OTLP           string
+	ExportInterval time.Duration
+}
+
+func newFlagSet(name string, o *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&o.URL, "url", "", "URL of the service to be tested")
+	fs.IntVar(&o.Requests, "requests", 0, "Number of total requests")
+	fs.IntVar(&o.Concurrency, "concurrency", 0, "Number of concurrent calls")
+	fs.DurationVar(&o.Duration, "duration", 0, "Run for this long instead of (or in addition to) a request count, e.g. 30s")
+	fs.Float64Var(&o.Rate, "rate", 0, "Send requests at a fixed rate (requests per second) independent of responses")
+	fs.StringVar(&o.Stages, "stages", "", "Ramp the rate in stages of duration:rps, e.g. 60s:500,2m:500")
+	fs.StringVar(&o.Method, "X", http.MethodGet, "HTTP method")
+	fs.Var(&o.Headers, "H", "Request header \"Name: value\" (repeatable)")
+	fs.StringVar(&o.Body, "body", "", "Request body, or @file to read it from a file")
+	fs.StringVar(&o.GRPCTarget, "grpc", "", "gRPC target host:port (replaces --url)")
+	fs.StringVar(&o.GRPCMethod, "grpc-method", "", "Fully qualified gRPC method, e.g. pb.OrderService/CreateOrder")
+	fs.Var(&o.ProtoFiles, "proto", "Local .proto file describing the gRPC method, instead of server reflection (repeatable)")
+	fs.Var(&o.ImportPaths, "import-path", "Import path for --proto files (repeatable)")
+	fs.BoolVar(&o.GRPCTLS, "grpc-tls", false, "Use TLS for the gRPC connection")
+	fs.StringVar(&o.WS, "ws", "", "WebSocket URL to hold --concurrency connections open against for --duration (replaces --url)")
+	fs.StringVar(&o.SSE, "sse", "", "Server-sent events URL to hold --concurrency streams open against for --duration (replaces --url)")
+	fs.StringVar(&o.Message, "message", "", "WebSocket message template sent by every connection at --message-rate")
+	fs.Float64Var(&o.MessageRate, "message-rate", 0, "WebSocket messages per second sent by each connection")
+	fs.StringVar(&o.Scenario, "scenario", "", "YAML or JSON file describing multi-step scenarios (replaces --url)")
+	fs.StringVar(&o.Targets, "targets", "", "File listing weighted URLs, one \"[weight] [METHOD] URL\" per line or as YAML/JSON (replaces --url)")
+	fs.StringVar(&o.Pick, "pick", loadtest.PickRandom, "How --targets are chosen for each request: random or round-robin")
+	fs.StringVar(&o.Replay, "replay", "", "HAR file or common/combined access log to replay against --url as base URL")
+	fs.Float64Var(&o.ReplaySpeed, "replay-speed", 1, "Replay pace relative to the recording, e.g. 2 for twice as fast (0 sends as fast as possible)")
+	fs.StringVar(&o.DataFile, "data-file", "", "CSV file whose rows are available to templates as {{.Row.column}}")
+	fs.DurationVar(&o.Timeout, "timeout", 10*time.Second, "Total timeout of each request")
+	fs.DurationVar(&o.ConnectTimeout, "connect-timeout", 5*time.Second, "Timeout for establishing a TCP connection")
+	fs.DurationVar(&o.TLSTimeout, "tls-timeout", 10*time.Second, "Timeout for the TLS handshake")
+	fs.StringVar(&o.Protocol, "http", "", "Force the HTTP protocol: 1.1, 2 or h2c (default negotiated)")
+	fs.BoolVar(&o.DisableKeepAlive, "disable-keepalive", false, "Open a new connection for every request")
+	fs.IntVar(&o.MaxConnsPerHost, "max-conns-per-host", 0, "Maximum connections per host (0 means unlimited)")
+	fs.BoolVar(&o.Insecure, "insecure", false, "Skip TLS certificate verification")
+	fs.StringVar(&o.CertFile, "cert", "", "Client certificate file (PEM) for mutual TLS")
+	fs.StringVar(&o.KeyFile, "key", "", "Client private key file (PEM) for mutual TLS")
+	fs.DurationVar(&o.Progress, "progress", time.Second, "Interval of the live progress line on stderr (0 disables it)")
+	fs.Var(&o.Assertions, "assert", "Response check such as status=200,201, body-contains=ok, body-regex=..., jsonpath=$.ok=true, header=X-Id or max-latency=500ms (repeatable)")
+	fs.Var(&o.Thresholds, "threshold", "Pass/fail condition such as p95<300ms, error_rate<1% or status_429<5% (repeatable)")
+	fs.StringVar(&o.Output, "output", "", "Machine-readable report format: json, csv or junit")
+	fs.StringVar(&o.OutputFile, "output-file", "", "File for the machine-readable report (default stdout)")
+	fs.StringVar(&o.HTML, "html", "", "Write a self-contained HTML report with charts to this file")
+	fs.StringVar(&o.Pushgateway, "pushgateway", "", "Push live metrics to this Prometheus pushgateway URL")
+	fs.StringVar(&o.OTLP, "otlp", "", "Push live metrics to this OTLP/HTTP endpoint, e.g. http://localhost:4318")
+	fs.DurationVar(&o.ExportInterval, "export-interval", 5*time.Second, "Interval of the --pushgateway and --otlp pushes")
+	return fs
+}
+
+const usage = "Usage: --url=<url>|--targets=<file>|--scenario=<file>|--grpc=<host:port>|--replay=<file> --url=<base url>|--ws=<url>|--sse=<url> --concurrency=<concurrency> [--requests=<requests>] [--duration=<duration>] [--rate=<rps>] [--stages=<duration:rps,...>]"
+
+// LoadConfig validates the load flags and the choice of target.
+func (o *Options) LoadConfig() (loadtest.LoadConfig, error) {
+	stages, err := loadtest.ParseStages(o.Stages)
+	if err != nil {
+		return loadtest.LoadConfig{}, err
+	}
+	cfg := loadtest.LoadConfig{
+		Requests:    o.Requests,
+		Concurrency: o.Concurrency,
+		Duration:    o.Duration,
+		Rate:        o.Rate,
+		Stages:      stages,
+	}
+	if countSet(o.URL, o.Targets, o.Scenario, o.GRPCTarget, o.WS, o.SSE) != 1 {
+		return cfg, fmt.Errorf("exactly one of --url, --targets, --scenario, --grpc, --ws or --sse must be set")
+	}
+	if o.Pick != loadtest.PickRandom && o.Targets == "" {
+		return cfg, fmt.Errorf("--pick needs --targets")
+	}
+	if o.Streaming() {
+		if err := o.streamConfig(cfg); err != nil {
+			return cfg, err
+		}
+	} else if o.Message != "" || o.MessageRate != 0 {
+		return cfg, fmt.Errorf("--message and --message-rate need --ws")
+	}
+	if (o.Pushgateway != "" || o.OTLP != "") && o.ExportInterval <= 0 {
+		return cfg, fmt.Errorf("--export-interval must be greater than zero")
+	}
+	if o.Replay != "" {
+		if err := o.replayConfig(&cfg); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// replayConfig loads the recording and sizes the run after it: each request
+// is replayed once, on its original schedule scaled by --replay-speed.
+func (o *Options) replayConfig(cfg *loadtest.LoadConfig) error {
+	if o.URL == "" {
+		return fmt.Errorf("--replay needs --url as the base URL of the target")
+	}
+	if o.ReplaySpeed < 0 {
+		return fmt.Errorf("--replay-speed must not be negative")
+	}
+	if cfg.Rate > 0 || len(cfg.Stages) > 0 {
+		return fmt.Errorf("--replay cannot be combined with --rate or --stages")
+	}
+	if o.replay == nil {
+		entries, err := loadtest.LoadReplay(o.Replay)
+		if err != nil {
+			return err
+		}
+		o.replay = entries
+	}
+	if o.ReplaySpeed == 0 {
+		// As fast as possible: closed-loop, wrapping around the recording
+		// when more requests or a duration are asked for
+		if cfg.Requests == 0 && cfg.Duration == 0 {
+			cfg.Requests = len(o.replay)
+		}
+		return nil
+	}
+	if cfg.Requests > len(o.replay) {
+		return fmt.Errorf("the recording has only %d requests", len(o.replay))
+	}
+	if cfg.Requests == 0 {
+		cfg.Requests = len(o.replay)
+	}
+	cfg.Schedule = loadtest.ReplaySchedule(o.replay, o.ReplaySpeed)
+	return nil
+}
+
+// Streaming reports whether the flags select a WebSocket or SSE test, which
+// holds connections open instead of sending requests.
+func (o *Options) Streaming() bool {
+	return o.WS != "" || o.SSE != ""
+}
+
+// streamConfig checks the flags that do not apply to streaming tests.
+func (o *Options) streamConfig(cfg loadtest.LoadConfig) error {
+	switch {
+	case cfg.Duration <= 0:
+		return fmt.Errorf("--ws and --sse need --duration")
+	case cfg.Requests > 0 || cfg.Rate > 0 || len(cfg.Stages) > 0 || o.Replay != "":
+		return fmt.Errorf("--requests, --rate, --stages and --replay do not apply to --ws or --sse")
+	case o.SSE != "" && (o.Message != "" || o.MessageRate != 0):
+		return fmt.Errorf("--message and --message-rate need --ws")
+	case o.MessageRate < 0:
+		return fmt.Errorf("--message-rate must not be negative")
+	case (o.Message != "") != (o.MessageRate > 0):
+		return fmt.Errorf("--message and --message-rate must be set together")
+	case o.Output != "" && o.Output != "json":
+		return fmt.Errorf("--ws and --sse only support json output")
+	case o.HTML != "" || len(o.Thresholds) > 0 || len(o.Assertions) > 0:
+		return fmt.Errorf("--html, --threshold and --assert do not apply to --ws or --sse")
+	case o.Pushgateway != "" || o.OTLP != "":
+		return fmt.Errorf("--pushgateway and --otlp do not apply to --ws or --sse")
+	}
+	return nil
+}
+
+// StreamSpec describes the streaming test selected by the flags.
+func (o *Options) StreamSpec(cfg loadtest.LoadConfig) loadtest.StreamSpec {
+	spec := loadtest.StreamSpec{
+		Kind:           loadtest.StreamSSE,
+		URL:            o.SSE,
+		Headers:        o.Headers,
+		Connections:    cfg.Concurrency,
+		Duration:       cfg.Duration,
+		Message:        o.Message,
+		MessageRate:    o.MessageRate,
+		ConnectTimeout: o.ConnectTimeout,
+		Insecure:       o.Insecure,
+	}
+	if o.WS != "" {
+		spec.Kind, spec.URL = loadtest.StreamWebSocket, o.WS
+	}
+	return spec
+}
+
+// breakdown names the groups the report breaks the stats down into.
+func (o *Options) breakdown() string {
+	if o.Targets != "" {
+		return "Target"
+	}
+	return "Step"
+}
+
+// NewTarget builds the Target selected by the flags and a description of it
+// for the report header.
+func (o *Options) NewTarget(ctx context.Context) (loadtest.Target, string, error) {
+	switch {
+	case o.Scenario != "":
+		target, err := loadtest.LoadScenarios(o.Scenario, o.Assertions)
+		return target, o.description(), err
+	case o.Targets != "":
+		target, err := loadtest.LoadTargets(o.Targets, o.Pick, loadtest.RequestSpec{
+			Method:     o.Method,
+			Headers:    o.Headers,
+			DataFile:   o.DataFile,
+			Assertions: o.Assertions,
+		})
+		return target, o.description(), err
+	case o.GRPCTarget != "":
+		target, err := loadtest.NewGRPCTarget(ctx, loadtest.GRPCSpec{
+			Target:      o.GRPCTarget,
+			Method:      o.GRPCMethod,
+			Body:        o.Body,
+			DataFile:    o.DataFile,
+			Headers:     o.Headers,
+			ProtoFiles:  o.ProtoFiles,
+			ImportPaths: o.ImportPaths,
+			TLS:         o.GRPCTLS,
+			Insecure:    o.Insecure,
+			Timeout:     o.Timeout,
+			Assertions:  o.Assertions,
+		})
+		return target, o.description(), err
+	case o.Replay != "":
+		if o.replay == nil {
+			entries, err := loadtest.LoadReplay(o.Replay)
+			if err != nil {
+				return nil, "", err
+			}
+			o.replay = entries
+		}
+		target, err := loadtest.NewReplayTarget(o.URL, o.replay, o.Assertions)
+		return target, o.description(), err
+	default:
+		target, err := loadtest.NewRequestTemplate(loadtest.RequestSpec{
+			Method:     o.Method,
+			URL:        o.URL,
+			Headers:    o.Headers,
+			Body:       o.Body,
+			DataFile:   o.DataFile,
+			Assertions: o.Assertions,
+		})
+		return target, o.description(), err
+	}
+}
+
+// closeTarget releases what a target holds open, such as the connection of
+// a gRPC target.
+func closeTarget(target loadtest.Target) {
+	if closer, ok := target.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// description names the target in the report header.
+func (o *Options) description() string {
+	switch {
+	case o.Scenario != "":
+		return "scenario " + o.Scenario
+	case o.Targets != "":
+		return "targets " + o.Targets + " (" + o.Pick + ")"
+	case o.GRPCTarget != "":
+		return "gRPC " + o.GRPCTarget + " " + o.GRPCMethod
+	case o.Replay != "":
+		return "replay of " + o.Replay + " against " + o.URL
+	case o.WS != "":
+		return "WebSocket " + o.WS
+	case o.SSE != "":
+		return "SSE " + o.SSE
+	default:
+		return strings.ToUpper(o.Method) + " " + o.URL
+	}
+}
+
+func (o *Options) NewClient() (*http.Client, error) {
+	return loadtest.NewHTTPClient(o.clientConfig())
+}
+
+func (o *Options) clientConfig() loadtest.ClientConfig {
+	return loadtest.ClientConfig{
+		Timeout:            o.Timeout,
+		ConnectTimeout:     o.ConnectTimeout,
+		TLSTimeout:         o.TLSTimeout,
+		Protocol:           o.Protocol,
+		DisableKeepAlives:  o.DisableKeepAlive,
+		MaxConnsPerHost:    o.MaxConnsPerHost,
+		InsecureSkipVerify: o.Insecure,
+		CertFile:           o.CertFile,
+		KeyFile:            o.KeyFile,
+	}
+}
+
+// targetName identifies the tested endpoint in machine-readable reports.
+func (o *Options) targetName() string {
+	switch {
+	case o.GRPCTarget != "":
+		return o.GRPCTarget + "/" + o.GRPCMethod
+	case o.Streaming():
+		return o.WS + o.SSE
+	case o.Targets != "":
+		return o.Targets
+	}
+	return o.URL
+}
+
+// printHeader prints the plan of the run before it starts.
+func (o *Options) printHeader(w io.Writer, description string, cfg loadtest.LoadConfig) {
+	fmt.Fprintf(w, "Starting stress test for %s\n", description)
+	if cfg.Requests > 0 && o.Scenario != "" {
+		fmt.Fprintf(w, "Total iterations: %d\n", cfg.Requests)
+	} else if cfg.Requests > 0 {
+		fmt.Fprintf(w, "Total requests: %d\n", cfg.Requests)
+	}
+	if d := cfg.RunDuration(); d > 0 {
+		fmt.Fprintf(w, "Duration: %v\n", d)
+	}
+	if o.Replay != "" {
+		if o.ReplaySpeed > 0 {
+			fmt.Fprintf(w, "Replay speed: %gx\n", o.ReplaySpeed)
+		} else {
+			fmt.Fprintln(w, "Replay speed: as fast as possible")
+		}
+	} else if cfg.OpenModel() {
+		fmt.Fprintf(w, "Rate: %g rps", cfg.Rate)
+		for _, s := range cfg.Stages {
+			fmt.Fprintf(w, " -> %g rps over %v", s.Target, s.Duration)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Concurrency level: %d\n", cfg.Concurrency)
+	fmt.Fprintln(w, "--------------------------------------------------")
+}
+
+// countSet returns how many of the values are not empty.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// headerFlags collects repeatable -H "Name: value" flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// stringsFlag collects a repeatable string flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// runProgress prints the status line rendered by line every interval until
+// ctx is done. On a terminal the line is rewritten in place.
+func runProgress(ctx context.Context, w io.Writer, interval time.Duration, line func(time.Time) string) {
+	tty := isTerminal(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if tty {
+				fmt.Fprint(w, "\r\033[K")
+			}
+			return
+		case now := <-ticker.C:
+			if tty {
+				fmt.Fprintf(w, "\r\033[K%s", line(now))
+			} else {
+				fmt.Fprintln(w, line(now))
+			}
+		}
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
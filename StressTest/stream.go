@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Leandroschwab/full-cycle-go/StressTest/loadtest"
+)
+
+// runStream executes a streaming test and returns the process exit code.
+func runStream(opts *Options, spec loadtest.StreamSpec) int {
+	console := io.Writer(os.Stdout)
+	var out io.Writer
+	if opts.Output != "" {
+		out = os.Stdout
+		if opts.OutputFile != "" {
+			f, err := os.Create(opts.OutputFile)
+			if err != nil {
+				fmt.Printf("Error creating output file: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		} else {
+			console = os.Stderr
+		}
+	}
+
+	var client *http.Client
+	if spec.Kind == loadtest.StreamSSE {
+		// The stream lasts the whole run, so no total request timeout
+		cfg := opts.clientConfig()
+		cfg.Timeout = 0
+		var err error
+		if client, err = loadtest.NewHTTPClient(cfg); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(console, "Starting stress test for %s\n", opts.description())
+	fmt.Fprintf(console, "Connections: %d\n", spec.Connections)
+	fmt.Fprintf(console, "Duration: %v\n", spec.Duration)
+	if spec.Message != "" && spec.MessageRate > 0 {
+		fmt.Fprintf(console, "Messages: %g per second per connection\n", spec.MessageRate)
+	}
+	fmt.Fprintln(console, "--------------------------------------------------")
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	stats := loadtest.NewStreamStats()
+	startTime := time.Now()
+	stopProgress := startProgress(stats.Line(startTime, spec.Duration), opts.Progress)
+	err := loadtest.RunStreams(ctx, spec, client, stats)
+	totalDuration := time.Since(startTime)
+	stopProgress()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	if ctx.Err() != nil {
+		fmt.Fprintln(console, "Interrupted: closed the open connections")
+	}
+	loadtest.PrintStreamReport(console, stats, totalDuration)
+	if out != nil {
+		summary := loadtest.NewStreamSummary(spec, startTime, totalDuration, stats)
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing json report: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
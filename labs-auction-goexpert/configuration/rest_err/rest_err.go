@@ -27,6 +27,8 @@ func ConvertError(internalError *internal_error.InternalError) *RestErr {
 		return NewBadRequestError(internalError.Error())
 	case "not_found":
 		return NewNotFoundError(internalError.Error())
+	case "conflict":
+		return NewConflictError(internalError.Error())
 	default:
 		return NewInternalServerError(internalError.Error())
 	}
@@ -50,6 +52,15 @@ func NewInternalServerError(message string) *RestErr {
 	}
 }
 
+func NewConflictError(message string) *RestErr {
+	return &RestErr{
+		Message: message,
+		Err:     "conflict",
+		Code:    http.StatusConflict,
+		Causes:  nil,
+	}
+}
+
 func NewNotFoundError(message string) *RestErr {
 	return &RestErr{
 		Message: message,
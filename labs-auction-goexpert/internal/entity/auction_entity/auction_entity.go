@@ -2,22 +2,40 @@ package auction_entity
 
 import (
 	"context"
+	"fmt"
 	"fullcycle-auction_go/internal/internal_error"
 	"github.com/google/uuid"
+	"os"
 	"time"
 )
 
 func CreateAuction(
 	productName, category, description string,
-	condition ProductCondition) (*Auction, *internal_error.InternalError) {
+	condition ProductCondition,
+	startingPrice, minimumIncrement float64,
+	startTime, endTime time.Time,
+	softCloseWindow, softCloseExtension time.Duration) (*Auction, *internal_error.InternalError) {
+	now := time.Now()
+	status := Active
+	if startTime.After(now) {
+		status = Scheduled
+	}
+
 	auction := &Auction{
-		Id:          uuid.New().String(),
-		ProductName: productName,
-		Category:    category,
-		Description: description,
-		Condition:   condition,
-		Status:      Active,
-		Timestamp:   time.Now(),
+		Id:               uuid.New().String(),
+		ProductName:      productName,
+		Category:         category,
+		Description:      description,
+		Condition:        condition,
+		Status:           status,
+		Timestamp:        now,
+		StartingPrice:    startingPrice,
+		MinimumIncrement: minimumIncrement,
+		StartTime:        startTime,
+		EndTime:          endTime,
+
+		SoftCloseWindow:    softCloseWindow,
+		SoftCloseExtension: softCloseExtension,
 	}
 
 	if err := auction.Validate(); err != nil {
@@ -35,6 +53,85 @@ func (au *Auction) Validate() *internal_error.InternalError {
 			au.Condition != Used) {
 		return internal_error.NewBadRequestError("invalid auction object")
 	}
+	if au.StartingPrice < 0 || au.MinimumIncrement < 0 {
+		return internal_error.NewBadRequestError("invalid auction object")
+	}
+	if au.SoftCloseWindow < 0 || au.SoftCloseExtension < 0 ||
+		(au.SoftCloseWindow > 0) != (au.SoftCloseExtension > 0) {
+		return internal_error.NewBadRequestError("Soft close needs both a window and an extension")
+	}
+	if au.SoftCloseWindow%time.Second != 0 || au.SoftCloseExtension%time.Second != 0 {
+		// End times are stored in whole seconds
+		return internal_error.NewBadRequestError("Soft close window and extension must be whole seconds")
+	}
+	if !au.EndTime.After(au.StartTime) || !au.EndTime.After(au.Timestamp) {
+		return internal_error.NewBadRequestError("Auction end time must be after its start time and in the future")
+	}
+
+	return nil
+}
+
+// DefaultDuration is how long an auction runs when it is created without an
+// end time, taken from AUCTION_INTERVAL and one minute when unset.
+func DefaultDuration() time.Duration {
+	duration, err := time.ParseDuration(os.Getenv("AUCTION_INTERVAL"))
+	if err != nil || duration <= 0 {
+		return time.Minute
+	}
+
+	return duration
+}
+
+var (
+	ErrAuctionNotStarted = internal_error.NewConflictError("Auction has not started")
+	ErrAuctionClosed     = internal_error.NewConflictError("Auction is closed")
+)
+
+// StatusAt is the status of the auction at t, which may be ahead of the
+// stored one until the scheduler updates it.
+func (au *Auction) StatusAt(t time.Time) AuctionStatus {
+	switch {
+	case au.Status == Completed || !t.Before(au.EndTime):
+		return Completed
+	case t.Before(au.StartTime):
+		return Scheduled
+	default:
+		return Active
+	}
+}
+
+// EndTimeAfterBid is the end time once a bid placed at t is accepted: with
+// soft close, a bid within the last SoftCloseWindow pushes the end out by
+// SoftCloseExtension.
+func (au *Auction) EndTimeAfterBid(t time.Time) time.Time {
+	if au.SoftCloseWindow > 0 && !t.Before(au.EndTime.Add(-au.SoftCloseWindow)) {
+		return au.EndTime.Add(au.SoftCloseExtension)
+	}
+	return au.EndTime
+}
+
+// ValidateBid checks a bid against the auction rules: the first bid must
+// reach the starting price, and later ones must beat the highest bid by at
+// least the minimum increment. A bid matching the highest amount wins the
+// tie only if it was placed earlier.
+func (au *Auction) ValidateBid(amount float64, timestamp time.Time) *internal_error.InternalError {
+	if au.HighestBid == nil {
+		if amount < au.StartingPrice {
+			return internal_error.NewBadRequestError(
+				fmt.Sprintf("Bid must be at least the starting price of %.2f", au.StartingPrice))
+		}
+		return nil
+	}
+
+	highest := au.HighestBid
+	if amount == highest.Amount && timestamp.Before(highest.Timestamp) {
+		return nil
+	}
+	if amount <= highest.Amount || amount < highest.Amount+au.MinimumIncrement {
+		return internal_error.NewBadRequestError(
+			fmt.Sprintf("Bid must be at least %.2f above the highest bid of %.2f",
+				au.MinimumIncrement, highest.Amount))
+	}
 
 	return nil
 }
@@ -47,6 +144,26 @@ type Auction struct {
 	Condition   ProductCondition
 	Status      AuctionStatus
 	Timestamp   time.Time
+
+	// Bids are accepted from StartTime until EndTime
+	StartTime time.Time
+	EndTime   time.Time
+
+	// SoftCloseWindow and SoftCloseExtension are the optional anti-sniping
+	// rule, see EndTimeAfterBid
+	SoftCloseWindow    time.Duration
+	SoftCloseExtension time.Duration
+
+	StartingPrice    float64
+	MinimumIncrement float64
+	HighestBid       *HighestBid
+}
+
+// HighestBid is the bid currently winning an auction.
+type HighestBid struct {
+	BidId     string
+	Amount    float64
+	Timestamp time.Time
 }
 
 type ProductCondition int
@@ -55,6 +172,7 @@ type AuctionStatus int
 const (
 	Active AuctionStatus = iota
 	Completed
+	Scheduled
 )
 
 const (
@@ -75,4 +193,8 @@ type AuctionRepositoryInterface interface {
 
 	FindAuctionById(
 		ctx context.Context, id string) (*Auction, *internal_error.InternalError)
+
+	PlaceBid(
+		ctx context.Context,
+		auctionId string, bid HighestBid) (time.Time, *internal_error.InternalError)
 }
@@ -0,0 +1,84 @@
+package auction_entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusAt(t *testing.T) {
+	now := time.Now()
+	auction := &Auction{Status: Scheduled, StartTime: now, EndTime: now.Add(time.Hour)}
+
+	if status := auction.StatusAt(now.Add(-time.Second)); status != Scheduled {
+		t.Errorf("expected Scheduled before the start time, got %v", status)
+	}
+	if status := auction.StatusAt(now.Add(time.Minute)); status != Active {
+		t.Errorf("expected Active between the start and end times, got %v", status)
+	}
+	if status := auction.StatusAt(now.Add(time.Hour)); status != Completed {
+		t.Errorf("expected Completed at the end time, got %v", status)
+	}
+
+	auction.Status = Completed
+	if status := auction.StatusAt(now.Add(time.Minute)); status != Completed {
+		t.Errorf("expected a completed auction to stay Completed, got %v", status)
+	}
+}
+
+func TestEndTimeAfterBid(t *testing.T) {
+	end := time.Now().Add(time.Hour)
+	auction := &Auction{EndTime: end}
+
+	if got := auction.EndTimeAfterBid(end.Add(-time.Second)); !got.Equal(end) {
+		t.Errorf("expected no extension without soft close, got %v", got)
+	}
+
+	auction.SoftCloseWindow = 30 * time.Second
+	auction.SoftCloseExtension = time.Minute
+	if got := auction.EndTimeAfterBid(end.Add(-31 * time.Second)); !got.Equal(end) {
+		t.Errorf("expected no extension before the window, got %v", got)
+	}
+	if got := auction.EndTimeAfterBid(end.Add(-30 * time.Second)); !got.Equal(end.Add(time.Minute)) {
+		t.Errorf("expected the end time pushed out by a minute, got %v", got)
+	}
+}
+
+func TestValidateBid(t *testing.T) {
+	now := time.Now()
+	auction := &Auction{Status: Active, StartingPrice: 100, MinimumIncrement: 10}
+
+	if err := auction.ValidateBid(99, now); err == nil {
+		t.Errorf("expected a bid below the starting price to be rejected")
+	}
+	if err := auction.ValidateBid(100, now); err != nil {
+		t.Errorf("expected a first bid at the starting price to be accepted, got %v", err)
+	}
+
+	auction.HighestBid = &HighestBid{BidId: "top", Amount: 100, Timestamp: now}
+	tests := []struct {
+		name      string
+		amount    float64
+		timestamp time.Time
+		accepted  bool
+	}{
+		{"below the increment", 109, now.Add(time.Second), false},
+		{"at the increment", 110, now.Add(time.Second), true},
+		{"lower bid", 50, now.Add(time.Second), false},
+		{"tie placed later", 100, now.Add(time.Millisecond), false},
+		{"tie placed earlier", 100, now.Add(-time.Millisecond), true},
+	}
+	for _, tt := range tests {
+		err := auction.ValidateBid(tt.amount, tt.timestamp)
+		if (err == nil) != tt.accepted {
+			t.Errorf("%s: expected accepted=%v, got error %v", tt.name, tt.accepted, err)
+		}
+	}
+
+	auction.MinimumIncrement = 0
+	if err := auction.ValidateBid(100, now.Add(time.Second)); err == nil {
+		t.Errorf("expected an equal later bid to be rejected without an increment")
+	}
+	if err := auction.ValidateBid(100.01, now.Add(time.Second)); err != nil {
+		t.Errorf("expected a higher bid to be accepted without an increment, got %v", err)
+	}
+}
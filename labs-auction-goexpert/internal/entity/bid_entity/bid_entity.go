@@ -43,10 +43,28 @@ func (b *Bid) Validate() *internal_error.InternalError {
 	return nil
 }
 
+type BidStatus string
+
+const (
+	BidAccepted              BidStatus = "accepted"
+	BidRejectedTooLow        BidStatus = "rejected_too_low"
+	BidRejectedAuctionClosed BidStatus = "rejected_auction_closed"
+	BidRejectedNotStarted    BidStatus = "rejected_auction_not_started"
+	BidFailed                BidStatus = "failed"
+)
+
+// BidResult is the outcome of one bid of a batch.
+type BidResult struct {
+	Status  BidStatus
+	Message string
+}
+
 type BidEntityRepository interface {
+	// CreateBid processes a batch of bids and returns the outcome of each,
+	// in the order of bidEntities
 	CreateBid(
 		ctx context.Context,
-		bidEntities []Bid) *internal_error.InternalError
+		bidEntities []Bid) ([]BidResult, *internal_error.InternalError)
 
 	FindBidByAuctionId(
 		ctx context.Context, auctionId string) ([]Bid, *internal_error.InternalError)
@@ -1,8 +1,8 @@
 package bid_controller
 
 import (
-	"context"
 	"fullcycle-auction_go/configuration/rest_err"
+	"fullcycle-auction_go/internal/entity/bid_entity"
 	"fullcycle-auction_go/internal/infra/api/web/validation"
 	"fullcycle-auction_go/internal/usecase/bid_usecase"
 	"github.com/gin-gonic/gin"
@@ -29,7 +29,7 @@ func (u *BidController) CreateBid(c *gin.Context) {
 		return
 	}
 
-	err := u.bidUseCase.CreateBid(context.Background(), bidInputDTO)
+	bidResult, err := u.bidUseCase.CreateBid(c.Request.Context(), bidInputDTO)
 	if err != nil {
 		restErr := rest_err.ConvertError(err)
 
@@ -37,5 +37,16 @@ func (u *BidController) CreateBid(c *gin.Context) {
 		return
 	}
 
-	c.Status(http.StatusCreated)
+	c.JSON(bidResultStatusCode(bidResult.Status), bidResult)
+}
+
+func bidResultStatusCode(status string) int {
+	switch bid_entity.BidStatus(status) {
+	case bid_entity.BidAccepted:
+		return http.StatusCreated
+	case bid_entity.BidRejectedTooLow, bid_entity.BidRejectedAuctionClosed, bid_entity.BidRejectedNotStarted:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -2,14 +2,12 @@ package auction
 
 import (
 	"context"
-	"fmt"
 	"fullcycle-auction_go/configuration/logger"
 	"fullcycle-auction_go/internal/entity/auction_entity"
 	"fullcycle-auction_go/internal/internal_error"
-	"os"
+	"sync"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -21,14 +19,38 @@ type AuctionEntityMongo struct {
 	Condition   auction_entity.ProductCondition `bson:"condition"`
 	Status      auction_entity.AuctionStatus    `bson:"status"`
 	Timestamp   int64                           `bson:"timestamp"`
+	StartTime   int64                           `bson:"start_time"`
+	EndTime     int64                           `bson:"end_time"`
+
+	// Soft close window and extension in seconds
+	SoftCloseWindow    int64 `bson:"soft_close_window"`
+	SoftCloseExtension int64 `bson:"soft_close_extension"`
+
+	StartingPrice    float64          `bson:"starting_price"`
+	MinimumIncrement float64          `bson:"minimum_increment"`
+	HighestBid       *HighestBidMongo `bson:"highest_bid"`
+}
+
+// HighestBidMongo keeps its timestamp in nanoseconds so ties between bids
+// placed within the same second are broken correctly.
+type HighestBidMongo struct {
+	BidId     string  `bson:"bid_id"`
+	Amount    float64 `bson:"amount"`
+	Timestamp int64   `bson:"timestamp"`
 }
+
 type AuctionRepository struct {
 	Collection *mongo.Collection
+
+	statusTimers      map[string]*time.Timer
+	statusTimersMutex *sync.Mutex
 }
 
 func NewAuctionRepository(database *mongo.Database) *AuctionRepository {
 	return &AuctionRepository{
-		Collection: database.Collection("auctions"),
+		Collection:        database.Collection("auctions"),
+		statusTimers:      make(map[string]*time.Timer),
+		statusTimersMutex: &sync.Mutex{},
 	}
 }
 
@@ -43,6 +65,14 @@ func (ar *AuctionRepository) CreateAuction(
 		Condition:   auctionEntity.Condition,
 		Status:      auctionEntity.Status,
 		Timestamp:   auctionEntity.Timestamp.Unix(),
+		StartTime:   auctionEntity.StartTime.Unix(),
+		EndTime:     auctionEntity.EndTime.Unix(),
+
+		SoftCloseWindow:    int64(auctionEntity.SoftCloseWindow / time.Second),
+		SoftCloseExtension: int64(auctionEntity.SoftCloseExtension / time.Second),
+
+		StartingPrice:    auctionEntity.StartingPrice,
+		MinimumIncrement: auctionEntity.MinimumIncrement,
 	}
 	_, err := ar.Collection.InsertOne(ctx, auctionEntityMongo)
 	if err != nil {
@@ -50,32 +80,6 @@ func (ar *AuctionRepository) CreateAuction(
 		return internal_error.NewInternalServerError("Error trying to insert auction")
 	}
 
-	go func() {
-		fmt.Println("Auction created successfully, starting timer to update status to completed")
-		select {
-		case <-time.After(getAuctionInterval()):
-			fmt.Println("Auction timer completed, updating status to completed")
-			update := bson.M{"$set": bson.M{"status": auction_entity.Completed}}
-			filter := bson.M{"_id": auctionEntityMongo.Id}
-			_, err := ar.Collection.UpdateOne(ctx, filter, update)
-			if err != nil {
-				fmt.Println("Error trying to update auction status to completed", err)
-				logger.Error("Error trying to update auction status to completed", err)
-				return
-			}
-			fmt.Println("Auction status updated to completed successfully")
-		}
-	}()
+	ar.scheduleAuction(auctionEntity)
 	return nil
 }
-
-func getAuctionInterval() time.Duration {
-	auctionInterval := os.Getenv("AUCTION_INTERVAL")
-	duration, err := time.ParseDuration(auctionInterval)
-	fmt.Println("Auction interval set to:", duration)
-	if err != nil {
-		logger.Error("Error parsing AUCTION_INTERVAL environment variable, using default value", err)
-		return time.Minute * 1
-	}
-	return duration
-}
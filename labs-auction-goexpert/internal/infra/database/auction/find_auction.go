@@ -21,15 +21,8 @@ func (ar *AuctionRepository) FindAuctionById(
 		return nil, internal_error.NewInternalServerError("Error trying to find auction by id")
 	}
 
-	return &auction_entity.Auction{
-		Id:          auctionEntityMongo.Id,
-		ProductName: auctionEntityMongo.ProductName,
-		Category:    auctionEntityMongo.Category,
-		Description: auctionEntityMongo.Description,
-		Condition:   auctionEntityMongo.Condition,
-		Status:      auctionEntityMongo.Status,
-		Timestamp:   time.Unix(auctionEntityMongo.Timestamp, 0),
-	}, nil
+	auctionEntity := auctionEntityMongo.toEntity()
+	return &auctionEntity, nil
 }
 
 func (repo *AuctionRepository) FindAuctions(
@@ -66,16 +59,41 @@ func (repo *AuctionRepository) FindAuctions(
 
 	var auctionsEntity []auction_entity.Auction
 	for _, auction := range auctionsMongo {
-		auctionsEntity = append(auctionsEntity, auction_entity.Auction{
-			Id:          auction.Id,
-			ProductName: auction.ProductName,
-			Category:    auction.Category,
-			Status:      auction.Status,
-			Description: auction.Description,
-			Condition:   auction.Condition,
-			Timestamp:   time.Unix(auction.Timestamp, 0),
-		})
+		auctionsEntity = append(auctionsEntity, auction.toEntity())
 	}
 
 	return auctionsEntity, nil
 }
+
+func (am *AuctionEntityMongo) toEntity() auction_entity.Auction {
+	auctionEntity := auction_entity.Auction{
+		Id:          am.Id,
+		ProductName: am.ProductName,
+		Category:    am.Category,
+		Description: am.Description,
+		Condition:   am.Condition,
+		Status:      am.Status,
+		Timestamp:   time.Unix(am.Timestamp, 0),
+		StartTime:   time.Unix(am.StartTime, 0),
+		EndTime:     time.Unix(am.EndTime, 0),
+
+		SoftCloseWindow:    time.Duration(am.SoftCloseWindow) * time.Second,
+		SoftCloseExtension: time.Duration(am.SoftCloseExtension) * time.Second,
+
+		StartingPrice:    am.StartingPrice,
+		MinimumIncrement: am.MinimumIncrement,
+	}
+	if am.EndTime == 0 {
+		// Created before auctions had their own schedule
+		auctionEntity.StartTime = auctionEntity.Timestamp
+		auctionEntity.EndTime = auctionEntity.Timestamp.Add(auction_entity.DefaultDuration())
+	}
+	if am.HighestBid != nil {
+		auctionEntity.HighestBid = &auction_entity.HighestBid{
+			BidId:     am.HighestBid.BidId,
+			Amount:    am.HighestBid.Amount,
+			Timestamp: time.Unix(0, am.HighestBid.Timestamp),
+		}
+	}
+	return auctionEntity
+}
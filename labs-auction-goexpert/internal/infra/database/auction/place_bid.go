@@ -0,0 +1,68 @@
+package auction
+
+import (
+	"context"
+	"fmt"
+	"fullcycle-auction_go/configuration/logger"
+	"fullcycle-auction_go/internal/entity/auction_entity"
+	"fullcycle-auction_go/internal/internal_error"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PlaceBid makes bid the highest bid of the auction if it passes the auction
+// rules. The update only applies while the highest bid is still the one the
+// rules were checked against, so concurrent bids, from this or any other
+// replica, cannot both win; the loser is checked again against the new
+// highest bid. Bids below the rules are bad requests; bids outside the
+// auction's start and end times are ErrAuctionNotStarted or ErrAuctionClosed.
+// It returns the end time of the auction, extended by the bid under a soft
+// close rule; only bids change it, so the same condition keeps it consistent.
+func (ar *AuctionRepository) PlaceBid(
+	ctx context.Context,
+	auctionId string, bid auction_entity.HighestBid) (time.Time, *internal_error.InternalError) {
+	for {
+		auctionEntity, err := ar.FindAuctionById(ctx, auctionId)
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch auctionEntity.StatusAt(bid.Timestamp) {
+		case auction_entity.Scheduled:
+			return auctionEntity.EndTime, auction_entity.ErrAuctionNotStarted
+		case auction_entity.Completed:
+			return auctionEntity.EndTime, auction_entity.ErrAuctionClosed
+		}
+		if err := auctionEntity.ValidateBid(bid.Amount, bid.Timestamp); err != nil {
+			return auctionEntity.EndTime, err
+		}
+		endTime := auctionEntity.EndTimeAfterBid(bid.Timestamp)
+
+		filter := bson.M{"_id": auctionId, "status": bson.M{"$ne": auction_entity.Completed}}
+		if auctionEntity.HighestBid == nil {
+			filter["highest_bid"] = nil
+		} else {
+			filter["highest_bid.bid_id"] = auctionEntity.HighestBid.BidId
+		}
+		update := bson.M{"$set": bson.M{
+			"highest_bid": HighestBidMongo{
+				BidId:     bid.BidId,
+				Amount:    bid.Amount,
+				Timestamp: bid.Timestamp.UnixNano(),
+			},
+			"end_time": endTime.Unix(),
+		}}
+
+		result, updateErr := ar.Collection.UpdateOne(ctx, filter, update)
+		if updateErr != nil {
+			logger.Error("Error trying to update the highest bid", updateErr)
+			return auctionEntity.EndTime, internal_error.NewInternalServerError("Error trying to update the highest bid")
+		}
+		if result.MatchedCount == 1 {
+			if endTime.After(auctionEntity.EndTime) {
+				logger.Info(fmt.Sprintf("Auction %s extended to %s by a late bid", auctionId, endTime))
+			}
+			return endTime, nil
+		}
+	}
+}
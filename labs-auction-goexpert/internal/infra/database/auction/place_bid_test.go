@@ -0,0 +1,56 @@
+package auction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fullcycle-auction_go/internal/entity/auction_entity"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPlaceBidExtendsAuctionInSoftCloseWindow(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(t)
+	repo := NewAuctionRepository(db)
+
+	now := time.Now()
+	auction := AuctionEntityMongo{
+		Id:                 "soft-close-auction-id",
+		Status:             auction_entity.Active,
+		Timestamp:          now.Add(-time.Hour).Unix(),
+		StartTime:          now.Add(-time.Hour).Unix(),
+		EndTime:            now.Add(10 * time.Second).Unix(),
+		SoftCloseWindow:    30,
+		SoftCloseExtension: 60,
+	}
+	if _, err := repo.Collection.InsertOne(ctx, auction); err != nil {
+		t.Fatalf("error inserting auction: %v", err)
+	}
+
+	endTime, err := repo.PlaceBid(ctx, auction.Id, auction_entity.HighestBid{
+		BidId:     "late-bid-id",
+		Amount:    100,
+		Timestamp: now,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	extended := time.Unix(auction.EndTime, 0).Add(time.Minute)
+	if !endTime.Equal(extended) {
+		t.Errorf("expected the end time to be extended to %v, got %v", extended, endTime)
+	}
+
+	var result AuctionEntityMongo
+	if err := repo.Collection.FindOne(ctx, bson.M{"_id": auction.Id}).Decode(&result); err != nil {
+		t.Fatalf("error fetching auction: %v", err)
+	}
+	if result.EndTime != extended.Unix() {
+		t.Errorf("expected the stored end time to be %v, got %v", extended, time.Unix(result.EndTime, 0))
+	}
+	if result.HighestBid == nil || result.HighestBid.BidId != "late-bid-id" {
+		t.Errorf("expected the late bid to be the highest bid, got %+v", result.HighestBid)
+	}
+}
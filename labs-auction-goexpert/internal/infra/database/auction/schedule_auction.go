@@ -0,0 +1,173 @@
+package auction
+
+import (
+	"context"
+	"fmt"
+	"fullcycle-auction_go/configuration/logger"
+	"fullcycle-auction_go/internal/entity/auction_entity"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// StartStatusScheduler opens and closes the auctions whose start or end time
+// passed while no replica was running and schedules the others from their
+// stored times. The scan repeats every AUCTION_SCAN_INTERVAL, so auctions
+// created by a replica that went down are still handled on time by the
+// remaining ones.
+func (ar *AuctionRepository) StartStatusScheduler(ctx context.Context) {
+	ar.scheduleOpenAuctions(ctx)
+
+	go func() {
+		ticker := time.NewTicker(getAuctionScanInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ar.scheduleOpenAuctions(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// scheduleOpenAuctions schedules every auction that is not completed yet.
+func (ar *AuctionRepository) scheduleOpenAuctions(ctx context.Context) {
+	filter := bson.M{"status": bson.M{"$ne": auction_entity.Completed}}
+	cursor, err := ar.Collection.Find(ctx, filter)
+	if err != nil {
+		logger.Error("Error trying to find open auctions", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var auctionsMongo []AuctionEntityMongo
+	if err := cursor.All(ctx, &auctionsMongo); err != nil {
+		logger.Error("Error trying to decode open auctions", err)
+		return
+	}
+
+	for _, auctionMongo := range auctionsMongo {
+		auctionEntity := auctionMongo.toEntity()
+		ar.scheduleAuction(&auctionEntity)
+	}
+}
+
+// scheduleAuction schedules the status changes of the auction: Scheduled to
+// Active at its start time and to Completed at its end time.
+func (ar *AuctionRepository) scheduleAuction(auctionEntity *auction_entity.Auction) {
+	if auctionEntity.Status == auction_entity.Scheduled {
+		ar.schedule("start/"+auctionEntity.Id, auctionEntity.StartTime, func() {
+			ar.updateStatus(context.Background(), auctionEntity.Id,
+				bson.M{"status": auction_entity.Scheduled}, auction_entity.Active)
+		})
+	}
+	ar.scheduleClose(auctionEntity.Id, closeTime(auctionEntity.EndTime))
+}
+
+// scheduleClose tries to close the auction at the given time. If a late bid
+// extended the auction in the meantime, it tries again at the new close
+// time; if the close failed otherwise, e.g. with the database down, it
+// retries after AUCTION_SCAN_INTERVAL instead of right away.
+func (ar *AuctionRepository) scheduleClose(auctionId string, at time.Time) {
+	ar.schedule("end/"+auctionId, at, func() {
+		ctx := context.Background()
+		if ar.closeAuction(ctx, auctionId) {
+			return
+		}
+
+		retryAt := time.Now().Add(getAuctionScanInterval())
+		auctionEntity, err := ar.FindAuctionById(ctx, auctionId)
+		if err == nil {
+			if auctionEntity.Status == auction_entity.Completed {
+				return
+			}
+			if extended := closeTime(auctionEntity.EndTime); extended.After(time.Now()) {
+				retryAt = extended
+			}
+		}
+		ar.scheduleClose(auctionId, retryAt)
+	})
+}
+
+// closeTime is when an auction ending at endTime is closed:
+// AUCTION_CLOSE_GRACE later, which leaves time for bids placed before the
+// end to leave their batch.
+func closeTime(endTime time.Time) time.Time {
+	return endTime.Add(getAuctionCloseGrace())
+}
+
+// schedule runs fn at the given time, right away if it already passed. A
+// key already scheduled by this replica is left as is.
+func (ar *AuctionRepository) schedule(key string, at time.Time, fn func()) {
+	ar.statusTimersMutex.Lock()
+	defer ar.statusTimersMutex.Unlock()
+
+	if _, ok := ar.statusTimers[key]; ok {
+		return
+	}
+
+	ar.statusTimers[key] = time.AfterFunc(time.Until(at), func() {
+		ar.statusTimersMutex.Lock()
+		delete(ar.statusTimers, key)
+		ar.statusTimersMutex.Unlock()
+
+		fn()
+	})
+}
+
+// closeAuction marks the auction Completed, whether or not it was opened,
+// once its end time is AUCTION_CLOSE_GRACE in the past; a late bid may have
+// pushed it out.
+func (ar *AuctionRepository) closeAuction(ctx context.Context, auctionId string) bool {
+	filter := bson.M{
+		"status": bson.M{"$ne": auction_entity.Completed},
+		"$or": bson.A{
+			bson.M{"end_time": bson.M{"$lte": time.Now().Add(-getAuctionCloseGrace()).Unix()}},
+			bson.M{"end_time": bson.M{"$exists": false}},
+		},
+	}
+	return ar.updateStatus(ctx, auctionId, filter, auction_entity.Completed)
+}
+
+// updateStatus sets the status of the auction if it still matches filter.
+// When several replicas race to change it exactly one succeeds and the
+// others leave it untouched.
+func (ar *AuctionRepository) updateStatus(
+	ctx context.Context, auctionId string, filter bson.M, to auction_entity.AuctionStatus) bool {
+	filter["_id"] = auctionId
+	update := bson.M{"$set": bson.M{"status": to}}
+
+	result, err := ar.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error trying to update the status of auction %s", auctionId), err)
+		return false
+	}
+	if result.ModifiedCount == 0 {
+		return false
+	}
+
+	logger.Info(fmt.Sprintf("Auction %s status changed to %d", auctionId, to))
+	return true
+}
+
+func getAuctionScanInterval() time.Duration {
+	duration, err := time.ParseDuration(os.Getenv("AUCTION_SCAN_INTERVAL"))
+	if err != nil || duration <= 0 {
+		return time.Minute
+	}
+
+	return duration
+}
+
+func getAuctionCloseGrace() time.Duration {
+	duration, err := time.ParseDuration(os.Getenv("AUCTION_CLOSE_GRACE"))
+	if err != nil || duration < 0 {
+		return 5 * time.Second
+	}
+
+	return duration
+}
@@ -0,0 +1,153 @@
+package auction
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"fullcycle-auction_go/internal/entity/auction_entity"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestStatusSchedulerHandlesOverdueAuctionsOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := openTestDatabase(t)
+
+	// Auctions that ended or started while no replica was running
+	overdue := AuctionEntityMongo{
+		Id:        "overdue-auction-id",
+		Status:    auction_entity.Active,
+		Timestamp: time.Now().Add(-time.Hour).Unix(),
+		StartTime: time.Now().Add(-time.Hour).Unix(),
+		EndTime:   time.Now().Add(-time.Minute).Unix(),
+	}
+	started := AuctionEntityMongo{
+		Id:        "started-auction-id",
+		Status:    auction_entity.Scheduled,
+		Timestamp: time.Now().Add(-time.Hour).Unix(),
+		StartTime: time.Now().Add(-time.Minute).Unix(),
+		EndTime:   time.Now().Add(time.Hour).Unix(),
+	}
+	for _, auction := range []AuctionEntityMongo{overdue, started} {
+		if _, err := db.Collection("auctions").InsertOne(ctx, auction); err != nil {
+			t.Fatalf("error inserting auction: %v", err)
+		}
+	}
+
+	// Two replicas starting at the same time
+	replica := NewAuctionRepository(db)
+	other := NewAuctionRepository(db)
+	replica.StartStatusScheduler(ctx)
+	other.StartStatusScheduler(ctx)
+
+	time.Sleep(500 * time.Millisecond)
+
+	var result AuctionEntityMongo
+	if err := replica.Collection.FindOne(ctx, bson.M{"_id": overdue.Id}).Decode(&result); err != nil {
+		t.Fatalf("error fetching auction: %v", err)
+	}
+	if result.Status != auction_entity.Completed {
+		t.Errorf("expected overdue auction status to be Completed, got %v", result.Status)
+	}
+	if replica.closeAuction(ctx, overdue.Id) || other.closeAuction(ctx, overdue.Id) {
+		t.Errorf("expected a completed auction not to be closed again")
+	}
+
+	if err := replica.Collection.FindOne(ctx, bson.M{"_id": started.Id}).Decode(&result); err != nil {
+		t.Fatalf("error fetching auction: %v", err)
+	}
+	if result.Status != auction_entity.Active {
+		t.Errorf("expected started auction status to be Active, got %v", result.Status)
+	}
+	if replica.closeAuction(ctx, started.Id) {
+		t.Errorf("expected an auction that has not ended not to be closed")
+	}
+}
+
+func TestScheduleCloseReschedulesExtendedAuction(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(t)
+	os.Setenv("AUCTION_CLOSE_GRACE", "0s")
+	defer os.Unsetenv("AUCTION_CLOSE_GRACE")
+
+	repo := NewAuctionRepository(db)
+	endTime := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	auction := AuctionEntityMongo{
+		Id:        "extended-auction-id",
+		Status:    auction_entity.Active,
+		Timestamp: time.Now().Add(-time.Hour).Unix(),
+		StartTime: time.Now().Add(-time.Hour).Unix(),
+		EndTime:   endTime.Unix(),
+	}
+	if _, err := repo.Collection.InsertOne(ctx, auction); err != nil {
+		t.Fatalf("error inserting auction: %v", err)
+	}
+	repo.scheduleClose(auction.Id, closeTime(endTime))
+
+	// A late bid extends the auction before the scheduled close runs
+	extended := endTime.Add(2 * time.Second)
+	if _, err := repo.Collection.UpdateOne(ctx, bson.M{"_id": auction.Id},
+		bson.M{"$set": bson.M{"end_time": extended.Unix()}}); err != nil {
+		t.Fatalf("error extending auction: %v", err)
+	}
+
+	var result AuctionEntityMongo
+	time.Sleep(time.Until(endTime.Add(time.Second)))
+	if err := repo.Collection.FindOne(ctx, bson.M{"_id": auction.Id}).Decode(&result); err != nil {
+		t.Fatalf("error fetching auction: %v", err)
+	}
+	if result.Status != auction_entity.Active {
+		t.Errorf("expected the extended auction to stay Active past its first end time, got %v", result.Status)
+	}
+
+	time.Sleep(time.Until(extended.Add(time.Second)))
+	if err := repo.Collection.FindOne(ctx, bson.M{"_id": auction.Id}).Decode(&result); err != nil {
+		t.Fatalf("error fetching auction: %v", err)
+	}
+	if result.Status != auction_entity.Completed {
+		t.Errorf("expected the extended auction to be Completed after its new end time, got %v", result.Status)
+	}
+}
+
+func TestScheduleCloseBacksOffWhileDatabaseIsDown(t *testing.T) {
+	os.Setenv("AUCTION_SCAN_INTERVAL", "1h")
+	defer os.Unsetenv("AUCTION_SCAN_INTERVAL")
+
+	// Nothing listens on port 1, so every operation fails quickly
+	client, err := mongo.Connect(context.Background(),
+		options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+	repo := NewAuctionRepository(client.Database("auction_test_db"))
+
+	const key = "end/down-auction-id"
+	timer := func() *time.Timer {
+		repo.statusTimersMutex.Lock()
+		defer repo.statusTimersMutex.Unlock()
+		return repo.statusTimers[key]
+	}
+
+	repo.scheduleClose("down-auction-id", time.Now())
+	first := timer()
+	deadline := time.Now().Add(2 * time.Second)
+	for timer() == first || timer() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the failed close to be scheduled again")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	retry := timer()
+	time.Sleep(500 * time.Millisecond)
+	if timer() != retry {
+		t.Error("expected the retry to wait for AUCTION_SCAN_INTERVAL, not to run right away")
+	}
+	retry.Stop()
+}
@@ -2,16 +2,17 @@ package bid
 
 import (
 	"context"
+	"fmt"
 	"fullcycle-auction_go/configuration/logger"
 	"fullcycle-auction_go/internal/entity/auction_entity"
 	"fullcycle-auction_go/internal/entity/bid_entity"
-	"fullcycle-auction_go/internal/infra/database/auction"
 	"fullcycle-auction_go/internal/internal_error"
-	"os"
 	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type BidEntityMongo struct {
@@ -22,100 +23,147 @@ type BidEntityMongo struct {
 	Timestamp int64   `bson:"timestamp"`
 }
 
+// bidWriter is the part of the bids collection CreateBid writes through.
+type bidWriter interface {
+	InsertOne(ctx context.Context, document interface{},
+		opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	DeleteOne(ctx context.Context, filter interface{},
+		opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+}
+
 type BidRepository struct {
 	Collection            *mongo.Collection
-	AuctionRepository     *auction.AuctionRepository
-	auctionInterval       time.Duration
+	AuctionRepository     auction_entity.AuctionRepositoryInterface
+	bids                  bidWriter
 	auctionStatusMap      map[string]auction_entity.AuctionStatus
 	auctionEndTimeMap     map[string]time.Time
 	auctionStatusMapMutex *sync.Mutex
 	auctionEndTimeMutex   *sync.Mutex
 }
 
-func NewBidRepository(database *mongo.Database, auctionRepository *auction.AuctionRepository) *BidRepository {
+func NewBidRepository(
+	database *mongo.Database,
+	auctionRepository auction_entity.AuctionRepositoryInterface) *BidRepository {
+	collection := database.Collection("bids")
 	return &BidRepository{
-		auctionInterval:       getAuctionInterval(),
 		auctionStatusMap:      make(map[string]auction_entity.AuctionStatus),
 		auctionEndTimeMap:     make(map[string]time.Time),
 		auctionStatusMapMutex: &sync.Mutex{},
 		auctionEndTimeMutex:   &sync.Mutex{},
-		Collection:            database.Collection("bids"),
+		Collection:            collection,
 		AuctionRepository:     auctionRepository,
+		bids:                  collection,
 	}
 }
 
 func (bd *BidRepository) CreateBid(
 	ctx context.Context,
-	bidEntities []bid_entity.Bid) *internal_error.InternalError {
+	bidEntities []bid_entity.Bid) ([]bid_entity.BidResult, *internal_error.InternalError) {
+	results := make([]bid_entity.BidResult, len(bidEntities))
 	var wg sync.WaitGroup
-	for _, bid := range bidEntities {
+	for i, bid := range bidEntities {
 		wg.Add(1)
-		go func(bidValue bid_entity.Bid) {
+		go func(bidValue bid_entity.Bid, result *bid_entity.BidResult) {
 			defer wg.Done()
+			*result = bd.createBid(ctx, bidValue)
+		}(bid, &results[i])
+	}
+	wg.Wait()
+	return results, nil
+}
 
-			bd.auctionStatusMapMutex.Lock()
-			auctionStatus, okStatus := bd.auctionStatusMap[bidValue.AuctionId]
-			bd.auctionStatusMapMutex.Unlock()
-
-			bd.auctionEndTimeMutex.Lock()
-			auctionEndTime, okEndTime := bd.auctionEndTimeMap[bidValue.AuctionId]
-			bd.auctionEndTimeMutex.Unlock()
-
-			bidEntityMongo := &BidEntityMongo{
-				Id:        bidValue.Id,
-				UserId:    bidValue.UserId,
-				AuctionId: bidValue.AuctionId,
-				Amount:    bidValue.Amount,
-				Timestamp: bidValue.Timestamp.Unix(),
-			}
-
-			if okEndTime && okStatus {
-				now := time.Now()
-				if auctionStatus == auction_entity.Completed || now.After(auctionEndTime) {
-					return
-				}
-
-				if _, err := bd.Collection.InsertOne(ctx, bidEntityMongo); err != nil {
-					logger.Error("Error trying to insert bid", err)
-					return
-				}
-
-				return
-			}
-
-			auctionEntity, err := bd.AuctionRepository.FindAuctionById(ctx, bidValue.AuctionId)
-			if err != nil {
-				logger.Error("Error trying to find auction by id", err)
-				return
-			}
-			if auctionEntity.Status == auction_entity.Completed {
-				return
-			}
+// createBid stores the bid and then offers it to the auction as the highest
+// bid, deleting it again if the auction rejects it. Storing it first means a
+// failed insert never leaves the auction won by a bid that does not exist.
+func (bd *BidRepository) createBid(ctx context.Context, bidValue bid_entity.Bid) bid_entity.BidResult {
+	bd.auctionStatusMapMutex.Lock()
+	auctionStatus, okStatus := bd.auctionStatusMap[bidValue.AuctionId]
+	bd.auctionStatusMapMutex.Unlock()
+
+	bd.auctionEndTimeMutex.Lock()
+	auctionEndTime, okEndTime := bd.auctionEndTimeMap[bidValue.AuctionId]
+	bd.auctionEndTimeMutex.Unlock()
+
+	bidEntityMongo := &BidEntityMongo{
+		Id:        bidValue.Id,
+		UserId:    bidValue.UserId,
+		AuctionId: bidValue.AuctionId,
+		Amount:    bidValue.Amount,
+		Timestamp: bidValue.Timestamp.Unix(),
+	}
 
+	// A bid is judged by the time it was placed, not by when its batch runs.
+	// Soft close only pushes end times out, so a bid placed before a cached
+	// end time is in time; otherwise the auction is read again in case a late
+	// bid extended it. Only a stored Completed status is cached: the auction
+	// may still take bids placed before its end that wait in a batch
+	if okStatus && auctionStatus == auction_entity.Completed {
+		return auctionClosedResult
+	}
+	if !okEndTime || !bidValue.Timestamp.Before(auctionEndTime) {
+		auctionEntity, err := bd.AuctionRepository.FindAuctionById(ctx, bidValue.AuctionId)
+		if err != nil {
+			logger.Error("Error trying to find auction by id", err)
+			return bid_entity.BidResult{Status: bid_entity.BidFailed, Message: err.Message}
+		}
+
+		if auctionEntity.Status == auction_entity.Completed {
 			bd.auctionStatusMapMutex.Lock()
-			bd.auctionStatusMap[bidValue.AuctionId] = auctionEntity.Status
+			bd.auctionStatusMap[bidValue.AuctionId] = auction_entity.Completed
 			bd.auctionStatusMapMutex.Unlock()
+		}
 
-			bd.auctionEndTimeMutex.Lock()
-			bd.auctionEndTimeMap[bidValue.AuctionId] = auctionEntity.Timestamp.Add(bd.auctionInterval)
-			bd.auctionEndTimeMutex.Unlock()
+		if auctionEntity.StatusAt(bidValue.Timestamp) == auction_entity.Completed {
+			return auctionClosedResult
+		}
 
-			if _, err := bd.Collection.InsertOne(ctx, bidEntityMongo); err != nil {
-				logger.Error("Error trying to insert bid", err)
-				return
-			}
-		}(bid)
+		bd.auctionEndTimeMutex.Lock()
+		bd.auctionEndTimeMap[bidValue.AuctionId] = auctionEntity.EndTime
+		bd.auctionEndTimeMutex.Unlock()
+	}
+
+	if _, err := bd.bids.InsertOne(ctx, bidEntityMongo); err != nil {
+		logger.Error("Error trying to insert bid", err)
+		return bid_entity.BidResult{Status: bid_entity.BidFailed, Message: "Error trying to insert bid"}
 	}
-	wg.Wait()
-	return nil
-}
 
-func getAuctionInterval() time.Duration {
-	auctionInterval := os.Getenv("AUCTION_INTERVAL")
-	duration, err := time.ParseDuration(auctionInterval)
+	endTime, err := bd.AuctionRepository.PlaceBid(ctx, bidValue.AuctionId, auction_entity.HighestBid{
+		BidId:     bidValue.Id,
+		Amount:    bidValue.Amount,
+		Timestamp: bidValue.Timestamp,
+	})
 	if err != nil {
-		return time.Minute * 5
+		logger.Info(fmt.Sprintf("Bid %s rejected: %s", bidValue.Id, err.Message))
+		if _, deleteErr := bd.bids.DeleteOne(ctx, bson.M{"_id": bidValue.Id}); deleteErr != nil {
+			logger.Error("Error trying to delete rejected bid", deleteErr)
+		}
+		return rejectedBidResult(err)
+	}
+
+	bd.auctionEndTimeMutex.Lock()
+	if endTime.After(bd.auctionEndTimeMap[bidValue.AuctionId]) {
+		bd.auctionEndTimeMap[bidValue.AuctionId] = endTime
 	}
+	bd.auctionEndTimeMutex.Unlock()
 
-	return duration
+	return bid_entity.BidResult{Status: bid_entity.BidAccepted}
+}
+
+var auctionClosedResult = bid_entity.BidResult{
+	Status:  bid_entity.BidRejectedAuctionClosed,
+	Message: "Auction is closed",
+}
+
+// rejectedBidResult maps a PlaceBid error to the bid outcome.
+func rejectedBidResult(err *internal_error.InternalError) bid_entity.BidResult {
+	switch {
+	case err == auction_entity.ErrAuctionNotStarted:
+		return bid_entity.BidResult{Status: bid_entity.BidRejectedNotStarted, Message: err.Message}
+	case err.Err == "bad_request":
+		return bid_entity.BidResult{Status: bid_entity.BidRejectedTooLow, Message: err.Message}
+	case err.Err == "conflict":
+		return bid_entity.BidResult{Status: bid_entity.BidRejectedAuctionClosed, Message: err.Message}
+	default:
+		return bid_entity.BidResult{Status: bid_entity.BidFailed, Message: err.Message}
+	}
 }
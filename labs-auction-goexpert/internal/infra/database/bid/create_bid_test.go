@@ -0,0 +1,160 @@
+package bid
+
+import (
+	"context"
+	"errors"
+	"fullcycle-auction_go/internal/entity/auction_entity"
+	"fullcycle-auction_go/internal/entity/bid_entity"
+	"fullcycle-auction_go/internal/internal_error"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// callLog records the writes of the fakes below in the order they happen.
+type callLog struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.mutex.Lock()
+	l.calls = append(l.calls, call)
+	l.mutex.Unlock()
+}
+
+// fakeAuctionRepository serves an active auction ending at end (in an hour
+// when unset) and accepts bids of at least minimum as the highest bid,
+// without soft close, so accepted bids leave the end time as is.
+type fakeAuctionRepository struct {
+	auction_entity.AuctionRepositoryInterface
+	log     *callLog
+	minimum float64
+	end     time.Time
+}
+
+func (f *fakeAuctionRepository) FindAuctionById(
+	ctx context.Context, id string) (*auction_entity.Auction, *internal_error.InternalError) {
+	return &auction_entity.Auction{
+		Id:        id,
+		Status:    auction_entity.Active,
+		StartTime: time.Now().Add(-time.Hour),
+		EndTime:   f.endTime(),
+	}, nil
+}
+
+func (f *fakeAuctionRepository) endTime() time.Time {
+	if f.end.IsZero() {
+		return time.Now().Add(time.Hour)
+	}
+	return f.end
+}
+
+func (f *fakeAuctionRepository) PlaceBid(
+	ctx context.Context,
+	auctionId string, bid auction_entity.HighestBid) (time.Time, *internal_error.InternalError) {
+	f.log.add("place")
+	if bid.Amount < f.minimum {
+		return time.Time{}, internal_error.NewBadRequestError("too low")
+	}
+	return f.endTime(), nil
+}
+
+// fakeBidWriter stands in for the bids collection, failing inserts on demand.
+type fakeBidWriter struct {
+	log       *callLog
+	insertErr error
+}
+
+func (f *fakeBidWriter) InsertOne(ctx context.Context, document interface{},
+	opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	f.log.add("insert")
+	if f.insertErr != nil {
+		return nil, f.insertErr
+	}
+	return &mongo.InsertOneResult{}, nil
+}
+
+func (f *fakeBidWriter) DeleteOne(ctx context.Context, filter interface{},
+	opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	f.log.add("delete")
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func newTestBidRepository(auctions auction_entity.AuctionRepositoryInterface, bids bidWriter) *BidRepository {
+	return &BidRepository{
+		AuctionRepository:     auctions,
+		bids:                  bids,
+		auctionStatusMap:      make(map[string]auction_entity.AuctionStatus),
+		auctionEndTimeMap:     make(map[string]time.Time),
+		auctionStatusMapMutex: &sync.Mutex{},
+		auctionEndTimeMutex:   &sync.Mutex{},
+	}
+}
+
+func TestCreateBidStoresBidBeforePlacingIt(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    float64
+		insertErr error
+		status    bid_entity.BidStatus
+		calls     []string
+	}{
+		{"accepted", 150, nil, bid_entity.BidAccepted, []string{"insert", "place"}},
+		{"insert fails", 150, errors.New("write failed"), bid_entity.BidFailed, []string{"insert"}},
+		{"rejected", 50, nil, bid_entity.BidRejectedTooLow, []string{"insert", "place", "delete"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &callLog{}
+			repository := newTestBidRepository(
+				&fakeAuctionRepository{log: log, minimum: 100},
+				&fakeBidWriter{log: log, insertErr: tt.insertErr})
+
+			results, err := repository.CreateBid(context.Background(), []bid_entity.Bid{{
+				Id:        "bid-id",
+				UserId:    "user-id",
+				AuctionId: "auction-id",
+				Amount:    tt.amount,
+				Timestamp: time.Now(),
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if results[0].Status != tt.status {
+				t.Errorf("expected %s, got %s", tt.status, results[0].Status)
+			}
+			if !reflect.DeepEqual(log.calls, tt.calls) {
+				t.Errorf("expected calls %v, got %v", tt.calls, log.calls)
+			}
+		})
+	}
+}
+
+func TestCreateBidJudgesBidsByTheirTimestamp(t *testing.T) {
+	log := &callLog{}
+	end := time.Now().Add(-time.Second)
+	repository := newTestBidRepository(
+		&fakeAuctionRepository{log: log, end: end},
+		&fakeBidWriter{log: log})
+
+	// The auction ended while both bids waited in the batch
+	results, err := repository.CreateBid(context.Background(), []bid_entity.Bid{
+		{Id: "early-bid-id", AuctionId: "auction-id", Amount: 100, Timestamp: end.Add(-time.Second)},
+		{Id: "late-bid-id", AuctionId: "auction-id", Amount: 200, Timestamp: end.Add(time.Millisecond)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Status != bid_entity.BidAccepted {
+		t.Errorf("expected the bid placed before the end to be accepted, got %s", results[0].Status)
+	}
+	if results[1].Status != bid_entity.BidRejectedAuctionClosed {
+		t.Errorf("expected the bid placed after the end to be rejected, got %s", results[1].Status)
+	}
+}
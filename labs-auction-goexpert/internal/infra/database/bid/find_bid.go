@@ -45,12 +45,24 @@ func (bd *BidRepository) FindBidByAuctionId(
 	return bidEntities, nil
 }
 
+// FindWinningBidByAuctionId returns the highest bid recorded on the auction.
+// Auctions without one, created before bids were ranked there, fall back to
+// the highest amount, earliest bid first on ties.
 func (bd *BidRepository) FindWinningBidByAuctionId(
 	ctx context.Context, auctionId string) (*bid_entity.Bid, *internal_error.InternalError) {
 	filter := bson.M{"auction_id": auctionId}
+	opts := options.FindOne().SetSort(bson.D{{Key: "amount", Value: -1}, {Key: "timestamp", Value: 1}})
+
+	auctionEntity, err := bd.AuctionRepository.FindAuctionById(ctx, auctionId)
+	if err != nil {
+		return nil, err
+	}
+	if auctionEntity.HighestBid != nil {
+		filter = bson.M{"_id": auctionEntity.HighestBid.BidId}
+		opts = options.FindOne()
+	}
 
 	var bidEntityMongo BidEntityMongo
-	opts := options.FindOne().SetSort(bson.D{{"amount", -1}})
 	if err := bd.Collection.FindOne(ctx, filter, opts).Decode(&bidEntityMongo); err != nil {
 		logger.Error("Error trying to find the auction winner", err)
 		return nil, internal_error.NewInternalServerError("Error trying to find the auction winner")
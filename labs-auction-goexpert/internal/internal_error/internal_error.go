@@ -23,6 +23,13 @@ func NewInternalServerError(message string) *InternalError {
 	}
 }
 
+func NewConflictError(message string) *InternalError {
+	return &InternalError{
+		Message: message,
+		Err:     "conflict",
+	}
+}
+
 func NewBadRequestError(message string) *InternalError {
 	return &InternalError{
 		Message: message,
@@ -6,6 +6,8 @@ import (
 	"fullcycle-auction_go/internal/entity/bid_entity"
 	"fullcycle-auction_go/internal/internal_error"
 	"fullcycle-auction_go/internal/usecase/bid_usecase"
+	"os"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +16,22 @@ type AuctionInputDTO struct {
 	Category    string           `json:"category" binding:"required,min=2"`
 	Description string           `json:"description" binding:"required,min=10,max=200"`
 	Condition   ProductCondition `json:"condition" binding:"oneof=0 1 2"`
+
+	StartingPrice float64 `json:"starting_price" binding:"min=0"`
+	// MinimumIncrement defaults to MINIMUM_BID_INCREMENT when omitted; an
+	// explicit 0 allows any bid above the highest one
+	MinimumIncrement *float64 `json:"minimum_increment" binding:"omitempty,min=0"`
+
+	// StartTime defaults to now; the auction ends at EndTime or after
+	// Duration (e.g. "2h"), by default after AUCTION_INTERVAL
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+	Duration  string     `json:"duration"`
+
+	// Optional anti-sniping rule: a bid within the last SoftCloseWindow
+	// (e.g. "30s") extends the auction by SoftCloseExtension (e.g. "1m")
+	SoftCloseWindow    string `json:"soft_close_window"`
+	SoftCloseExtension string `json:"soft_close_extension"`
 }
 
 type AuctionOutputDTO struct {
@@ -24,6 +42,14 @@ type AuctionOutputDTO struct {
 	Condition   ProductCondition `json:"condition"`
 	Status      AuctionStatus    `json:"status"`
 	Timestamp   time.Time        `json:"timestamp" time_format:"2006-01-02 15:04:05"`
+
+	StartingPrice    float64   `json:"starting_price"`
+	MinimumIncrement float64   `json:"minimum_increment"`
+	StartTime        time.Time `json:"start_time"`
+	EndTime          time.Time `json:"end_time"`
+
+	SoftCloseWindow    string `json:"soft_close_window,omitempty"`
+	SoftCloseExtension string `json:"soft_close_extension,omitempty"`
 }
 
 type WinningInfoOutputDTO struct {
@@ -69,11 +95,36 @@ type AuctionUseCase struct {
 func (au *AuctionUseCase) CreateAuction(
 	ctx context.Context,
 	auctionInput AuctionInputDTO) *internal_error.InternalError {
+	minimumIncrement := getMinimumBidIncrement()
+	if auctionInput.MinimumIncrement != nil {
+		minimumIncrement = *auctionInput.MinimumIncrement
+	}
+
+	startTime, endTime, err := auctionSchedule(auctionInput)
+	if err != nil {
+		return err
+	}
+
+	softCloseWindow, err := parseOptionalDuration(auctionInput.SoftCloseWindow, "soft_close_window")
+	if err != nil {
+		return err
+	}
+	softCloseExtension, err := parseOptionalDuration(auctionInput.SoftCloseExtension, "soft_close_extension")
+	if err != nil {
+		return err
+	}
+
 	auction, err := auction_entity.CreateAuction(
 		auctionInput.ProductName,
 		auctionInput.Category,
 		auctionInput.Description,
-		auction_entity.ProductCondition(auctionInput.Condition))
+		auction_entity.ProductCondition(auctionInput.Condition),
+		auctionInput.StartingPrice,
+		minimumIncrement,
+		startTime,
+		endTime,
+		softCloseWindow,
+		softCloseExtension)
 	if err != nil {
 		return err
 	}
@@ -85,3 +136,48 @@ func (au *AuctionUseCase) CreateAuction(
 
 	return nil
 }
+
+func auctionSchedule(
+	auctionInput AuctionInputDTO) (time.Time, time.Time, *internal_error.InternalError) {
+	startTime := time.Now()
+	if auctionInput.StartTime != nil {
+		startTime = *auctionInput.StartTime
+	}
+
+	switch {
+	case auctionInput.EndTime != nil && auctionInput.Duration != "":
+		return startTime, startTime, internal_error.NewBadRequestError("Set either end_time or duration, not both")
+	case auctionInput.EndTime != nil:
+		return startTime, *auctionInput.EndTime, nil
+	case auctionInput.Duration != "":
+		duration, err := time.ParseDuration(auctionInput.Duration)
+		if err != nil || duration <= 0 {
+			return startTime, startTime, internal_error.NewBadRequestError("Duration is not a valid value")
+		}
+		return startTime, startTime.Add(duration), nil
+	default:
+		return startTime, startTime.Add(auction_entity.DefaultDuration()), nil
+	}
+}
+
+func parseOptionalDuration(value, field string) (time.Duration, *internal_error.InternalError) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < time.Second || duration%time.Second != 0 {
+		return 0, internal_error.NewBadRequestError(field + " must be a whole number of seconds, at least 1s")
+	}
+
+	return duration, nil
+}
+
+func getMinimumBidIncrement() float64 {
+	value, err := strconv.ParseFloat(os.Getenv("MINIMUM_BID_INCREMENT"), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+
+	return value
+}
@@ -6,6 +6,7 @@ import (
 	"fullcycle-auction_go/internal/entity/auction_entity"
 	"fullcycle-auction_go/internal/internal_error"
 	"fullcycle-auction_go/internal/usecase/bid_usecase"
+	"time"
 )
 
 func (au *AuctionUseCase) FindAuctionById(
@@ -23,6 +24,13 @@ func (au *AuctionUseCase) FindAuctionById(
 		Condition:   ProductCondition(auctionEntity.Condition),
 		Status:      AuctionStatus(auctionEntity.Status),
 		Timestamp:   auctionEntity.Timestamp,
+
+		StartingPrice:      auctionEntity.StartingPrice,
+		MinimumIncrement:   auctionEntity.MinimumIncrement,
+		StartTime:          auctionEntity.StartTime,
+		EndTime:            auctionEntity.EndTime,
+		SoftCloseWindow:    durationString(auctionEntity.SoftCloseWindow),
+		SoftCloseExtension: durationString(auctionEntity.SoftCloseExtension),
 	}, nil
 }
 
@@ -46,6 +54,13 @@ func (au *AuctionUseCase) FindAuctions(
 			Condition:   ProductCondition(value.Condition),
 			Status:      AuctionStatus(value.Status),
 			Timestamp:   value.Timestamp,
+
+			StartingPrice:      value.StartingPrice,
+			MinimumIncrement:   value.MinimumIncrement,
+			StartTime:          value.StartTime,
+			EndTime:            value.EndTime,
+			SoftCloseWindow:    durationString(value.SoftCloseWindow),
+			SoftCloseExtension: durationString(value.SoftCloseExtension),
 		})
 	}
 
@@ -68,6 +83,13 @@ func (au *AuctionUseCase) FindWinningBidByAuctionId(
 		Condition:   ProductCondition(auction.Condition),
 		Status:      AuctionStatus(auction.Status),
 		Timestamp:   auction.Timestamp,
+
+		StartingPrice:      auction.StartingPrice,
+		MinimumIncrement:   auction.MinimumIncrement,
+		StartTime:          auction.StartTime,
+		EndTime:            auction.EndTime,
+		SoftCloseWindow:    durationString(auction.SoftCloseWindow),
+		SoftCloseExtension: durationString(auction.SoftCloseExtension),
 	}
 
 	bidWinning, err := au.bidRepositoryInterface.FindWinningBidByAuctionId(ctx, auction.Id)
@@ -92,3 +114,10 @@ func (au *AuctionUseCase) FindWinningBidByAuctionId(
 		Bid:     bidOutputDTO,
 	}, nil
 }
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -24,13 +24,28 @@ type BidOutputDTO struct {
 	Timestamp time.Time `json:"timestamp" time_format:"2006-01-02 15:04:05"`
 }
 
+// BidResultOutputDTO is the outcome of a bid: accepted, rejected_too_low,
+// rejected_auction_not_started, rejected_auction_closed or failed.
+type BidResultOutputDTO struct {
+	BidOutputDTO
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
 type BidUseCase struct {
 	BidRepository bid_entity.BidEntityRepository
 
 	timer               *time.Timer
 	maxBatchSize        int
 	batchInsertInterval time.Duration
-	bidChannel          chan bid_entity.Bid
+	maxWait             time.Duration
+	bidChannel          chan bidRequest
+}
+
+// bidRequest is a bid waiting for its batch; the outcome is sent on result.
+type bidRequest struct {
+	bid    bid_entity.Bid
+	result chan bid_entity.BidResult
 }
 
 func NewBidUseCase(bidRepository bid_entity.BidEntityRepository) BidUseCaseInterface {
@@ -41,8 +56,9 @@ func NewBidUseCase(bidRepository bid_entity.BidEntityRepository) BidUseCaseInter
 		BidRepository:       bidRepository,
 		maxBatchSize:        maxBatchSize,
 		batchInsertInterval: maxSizeInterval,
+		maxWait:             getBidMaxWait(),
 		timer:               time.NewTimer(maxSizeInterval),
-		bidChannel:          make(chan bid_entity.Bid, maxBatchSize),
+		bidChannel:          make(chan bidRequest, maxBatchSize),
 	}
 
 	bidUseCase.triggerCreateRoutine(context.Background())
@@ -50,12 +66,10 @@ func NewBidUseCase(bidRepository bid_entity.BidEntityRepository) BidUseCaseInter
 	return bidUseCase
 }
 
-var bidBatch []bid_entity.Bid
-
 type BidUseCaseInterface interface {
 	CreateBid(
 		ctx context.Context,
-		bidInputDTO BidInputDTO) *internal_error.InternalError
+		bidInputDTO BidInputDTO) (*BidResultOutputDTO, *internal_error.InternalError)
 
 	FindWinningBidByAuctionId(
 		ctx context.Context, auctionId string) (*BidOutputDTO, *internal_error.InternalError)
@@ -64,55 +78,105 @@ type BidUseCaseInterface interface {
 		ctx context.Context, auctionId string) ([]BidOutputDTO, *internal_error.InternalError)
 }
 
+// triggerCreateRoutine inserts the queued bids in batches, when a batch
+// reaches maxBatchSize, every batchInsertInterval, or once its first bid has
+// waited maxWait, so a request never waits long for a quiet batch.
 func (bu *BidUseCase) triggerCreateRoutine(ctx context.Context) {
 	go func() {
-		defer close(bu.bidChannel)
+		var bidBatch []bidRequest
+		var wait <-chan time.Time
 
 		for {
 			select {
-			case bidEntity, ok := <-bu.bidChannel:
+			case request, ok := <-bu.bidChannel:
 				if !ok {
-					if len(bidBatch) > 0 {
-						if err := bu.BidRepository.CreateBid(ctx, bidBatch); err != nil {
-							logger.Error("error trying to process bid batch list", err)
-						}
-					}
+					bu.processBatch(ctx, bidBatch)
 					return
 				}
 
-				bidBatch = append(bidBatch, bidEntity)
+				if len(bidBatch) == 0 {
+					wait = time.After(bu.maxWait)
+				}
+				bidBatch = append(bidBatch, request)
 
 				if len(bidBatch) >= bu.maxBatchSize {
-					if err := bu.BidRepository.CreateBid(ctx, bidBatch); err != nil {
-						logger.Error("error trying to process bid batch list", err)
-					}
+					bu.processBatch(ctx, bidBatch)
 
-					bidBatch = nil
+					bidBatch, wait = nil, nil
 					bu.timer.Reset(bu.batchInsertInterval)
 				}
+			case <-wait:
+				bu.processBatch(ctx, bidBatch)
+				bidBatch, wait = nil, nil
 			case <-bu.timer.C:
-				if err := bu.BidRepository.CreateBid(ctx, bidBatch); err != nil {
-					logger.Error("error trying to process bid batch list", err)
-				}
-				bidBatch = nil
+				bu.processBatch(ctx, bidBatch)
+				bidBatch, wait = nil, nil
 				bu.timer.Reset(bu.batchInsertInterval)
 			}
 		}
 	}()
 }
 
+// processBatch inserts the batch and sends each bid its outcome.
+func (bu *BidUseCase) processBatch(ctx context.Context, bidBatch []bidRequest) {
+	if len(bidBatch) == 0 {
+		return
+	}
+
+	bidEntities := make([]bid_entity.Bid, len(bidBatch))
+	for i, request := range bidBatch {
+		bidEntities[i] = request.bid
+	}
+
+	results, err := bu.BidRepository.CreateBid(ctx, bidEntities)
+	if err != nil {
+		logger.Error("error trying to process bid batch list", err)
+	}
+
+	for i, request := range bidBatch {
+		result := bid_entity.BidResult{Status: bid_entity.BidFailed, Message: "error trying to process bid batch list"}
+		if err == nil && i < len(results) {
+			result = results[i]
+		}
+		request.result <- result
+	}
+}
+
+// CreateBid queues the bid for the next batch and waits for its outcome, so
+// the response can take up to BID_MAX_WAIT when traffic is low.
 func (bu *BidUseCase) CreateBid(
 	ctx context.Context,
-	bidInputDTO BidInputDTO) *internal_error.InternalError {
+	bidInputDTO BidInputDTO) (*BidResultOutputDTO, *internal_error.InternalError) {
 
 	bidEntity, err := bid_entity.CreateBid(bidInputDTO.UserId, bidInputDTO.AuctionId, bidInputDTO.Amount)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	bu.bidChannel <- *bidEntity
+	request := bidRequest{bid: *bidEntity, result: make(chan bid_entity.BidResult, 1)}
+	select {
+	case bu.bidChannel <- request:
+	case <-ctx.Done():
+		return nil, internal_error.NewInternalServerError("Bid was canceled before being processed")
+	}
 
-	return nil
+	select {
+	case result := <-request.result:
+		return &BidResultOutputDTO{
+			BidOutputDTO: BidOutputDTO{
+				Id:        bidEntity.Id,
+				UserId:    bidEntity.UserId,
+				AuctionId: bidEntity.AuctionId,
+				Amount:    bidEntity.Amount,
+				Timestamp: bidEntity.Timestamp,
+			},
+			Status:  string(result.Status),
+			Message: result.Message,
+		}, nil
+	case <-ctx.Done():
+		// The bid is still processed with its batch; only the outcome is lost
+		return nil, internal_error.NewInternalServerError("Bid was canceled while waiting for its batch")
+	}
 }
 
 func getMaxBatchSizeInterval() time.Duration {
@@ -125,6 +189,15 @@ func getMaxBatchSizeInterval() time.Duration {
 	return duration
 }
 
+func getBidMaxWait() time.Duration {
+	duration, err := time.ParseDuration(os.Getenv("BID_MAX_WAIT"))
+	if err != nil || duration <= 0 {
+		return time.Second
+	}
+
+	return duration
+}
+
 func getMaxBatchSize() int {
 	value, err := strconv.Atoi(os.Getenv("MAX_BATCH_SIZE"))
 	if err != nil {
@@ -0,0 +1,118 @@
+package bid_usecase
+
+import (
+	"context"
+	"fullcycle-auction_go/internal/entity/bid_entity"
+	"fullcycle-auction_go/internal/internal_error"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeBidRepository accepts bids of at least minimum and records the batches.
+type fakeBidRepository struct {
+	minimum float64
+
+	mutex   sync.Mutex
+	batches [][]bid_entity.Bid
+}
+
+func (f *fakeBidRepository) CreateBid(
+	ctx context.Context,
+	bidEntities []bid_entity.Bid) ([]bid_entity.BidResult, *internal_error.InternalError) {
+	f.mutex.Lock()
+	f.batches = append(f.batches, bidEntities)
+	f.mutex.Unlock()
+
+	results := make([]bid_entity.BidResult, len(bidEntities))
+	for i, bid := range bidEntities {
+		results[i] = bid_entity.BidResult{Status: bid_entity.BidAccepted}
+		if bid.Amount < f.minimum {
+			results[i] = bid_entity.BidResult{Status: bid_entity.BidRejectedTooLow, Message: "too low"}
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeBidRepository) FindBidByAuctionId(
+	ctx context.Context, auctionId string) ([]bid_entity.Bid, *internal_error.InternalError) {
+	return nil, nil
+}
+
+func (f *fakeBidRepository) FindWinningBidByAuctionId(
+	ctx context.Context, auctionId string) (*bid_entity.Bid, *internal_error.InternalError) {
+	return nil, nil
+}
+
+func TestCreateBidReturnsOutcomeOfBatchedBid(t *testing.T) {
+	os.Setenv("MAX_BATCH_SIZE", "2")
+	os.Setenv("BATCH_INSERT_INTERVAL", "1m")
+	defer os.Unsetenv("MAX_BATCH_SIZE")
+	defer os.Unsetenv("BATCH_INSERT_INTERVAL")
+
+	repository := &fakeBidRepository{minimum: 100}
+	useCase := NewBidUseCase(repository)
+	auctionId := uuid.New().String()
+
+	amounts := []float64{150, 50}
+	statuses := make([]string, len(amounts))
+	var wg sync.WaitGroup
+	for i, amount := range amounts {
+		wg.Add(1)
+		go func(i int, amount float64) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			result, err := useCase.CreateBid(ctx, BidInputDTO{
+				UserId:    uuid.New().String(),
+				AuctionId: auctionId,
+				Amount:    amount,
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			statuses[i] = result.Status
+		}(i, amount)
+	}
+	wg.Wait()
+
+	if statuses[0] != string(bid_entity.BidAccepted) || statuses[1] != string(bid_entity.BidRejectedTooLow) {
+		t.Errorf("expected accepted and rejected_too_low, got %v", statuses)
+	}
+	if len(repository.batches) != 1 || len(repository.batches[0]) != 2 {
+		t.Errorf("expected both bids in one batch, got %v", repository.batches)
+	}
+}
+
+func TestCreateBidFlushesQuietBatchAfterMaxWait(t *testing.T) {
+	os.Setenv("MAX_BATCH_SIZE", "5")
+	os.Setenv("BATCH_INSERT_INTERVAL", "1m")
+	os.Setenv("BID_MAX_WAIT", "50ms")
+	defer os.Unsetenv("MAX_BATCH_SIZE")
+	defer os.Unsetenv("BATCH_INSERT_INTERVAL")
+	defer os.Unsetenv("BID_MAX_WAIT")
+
+	useCase := NewBidUseCase(&fakeBidRepository{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	result, err := useCase.CreateBid(ctx, BidInputDTO{
+		UserId:    uuid.New().String(),
+		AuctionId: uuid.New().String(),
+		Amount:    100,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != string(bid_entity.BidAccepted) {
+		t.Errorf("expected accepted, got %s", result.Status)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the lone bid to be flushed after BID_MAX_WAIT, waited %v", elapsed)
+	}
+}